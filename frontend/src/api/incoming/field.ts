@@ -0,0 +1,66 @@
+// Decoding of the packed preview fields sent by the backend (see src/api/field.go).
+
+export interface PackedField {
+	shape: [number, number, number]; // [nComp, ny, nx]
+	encoding: 'float32' | 'float16' | 'uint8';
+	min: number;
+	max: number;
+	data: Uint8Array;
+}
+
+// Field holds the decoded values, component by component and row by row, x varying fastest.
+// Cells without data are NaN.
+export interface Field {
+	nComp: number;
+	ny: number;
+	nx: number;
+	values: Float32Array;
+}
+
+export function decodeField(packed: PackedField): Field {
+	const [nComp, ny, nx] = packed.shape;
+	const n = nComp * ny * nx;
+	const values = new Float32Array(n);
+	const bytes = packed.data;
+	const view = new DataView(bytes.buffer, bytes.byteOffset, bytes.byteLength);
+	switch (packed.encoding) {
+		case 'float32':
+			for (let i = 0; i < n; i++) {
+				values[i] = view.getFloat32(4 * i, true);
+			}
+			break;
+		case 'float16':
+			for (let i = 0; i < n; i++) {
+				values[i] = float16ToNumber(view.getUint16(2 * i, true));
+			}
+			break;
+		case 'uint8': {
+			const scale = (packed.max - packed.min) / 254;
+			for (let i = 0; i < n; i++) {
+				values[i] = bytes[i] === 255 ? NaN : packed.min + bytes[i] * scale;
+			}
+			break;
+		}
+		default:
+			throw new Error(`Unsupported preview encoding: ${packed.encoding}`);
+	}
+	return { nComp, ny, nx, values };
+}
+
+function float16ToNumber(h: number): number {
+	const sign = h & 0x8000 ? -1 : 1;
+	const exp = (h >> 10) & 0x1f;
+	const mant = h & 0x3ff;
+	if (exp === 0) {
+		return sign * mant * 2 ** -24;
+	}
+	if (exp === 0x1f) {
+		return mant ? NaN : sign * Infinity;
+	}
+	return sign * (1 + mant / 1024) * 2 ** (exp - 15);
+}
+
+// fieldValue returns component c of the cell (x, y).
+export function fieldValue(f: Field, c: number, x: number, y: number): number {
+	return f.values[(c * f.ny + y) * f.nx + x];
+}
@@ -5,6 +5,7 @@ export interface Field {
 	value: string;
 	description: string;
 	changed: boolean;
+	map: string;
 }
 
 export interface Parameters {
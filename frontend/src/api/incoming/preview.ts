@@ -1,4 +1,5 @@
 import { writable } from 'svelte/store';
+import { decodeField, fieldValue, type PackedField } from './field';
 
 export type VectorField = Array<{ x: number; y: number; z: number }>;
 export type ScalarField = Array<Array<number>>;
@@ -9,9 +10,13 @@ export interface Preview {
 	component: string;
 	layer: number;
 	type: string;
-	vectorFieldValues: VectorField;
-	vectorFieldPositions: VectorField;
-	scalarField: ScalarField;
+	field: PackedField | null;
+	unchanged: boolean;
+	encoding: string;
+	// unpacked from field on reception
+	vectorFieldValues: VectorField | null;
+	vectorFieldPositions: VectorField | null;
+	scalarField: ScalarField | null;
 	min: number;
 	max: number;
 	refresh: boolean;
@@ -25,6 +30,20 @@ export interface Preview {
 	yChosenSize: number
 }
 
+// previewPane is the name of the backend preview pane shown in this tab. Every tab has its own,
+// so that several people watching the same run do not change each other's view.
+let pane = '';
+export function previewPane(): string {
+	if (pane === '') {
+		pane = sessionStorage.getItem('previewPane') ?? '';
+		if (pane === '') {
+			pane = 'tab-' + Math.random().toString(36).slice(2, 10);
+			sessionStorage.setItem('previewPane', pane);
+		}
+	}
+	return pane;
+}
+
 export const previewState = writable<Preview>({
 	quantity: '',
 	unit: '',
@@ -32,6 +51,9 @@ export const previewState = writable<Preview>({
 	layer: 0,
 	maxPoints: 0,
 	type: '',
+	field: null,
+	unchanged: false,
+	encoding: '',
 	vectorFieldValues: [],
 	vectorFieldPositions: [],
 	scalarField: [],
@@ -45,3 +67,49 @@ export const previewState = writable<Preview>({
 	xChosenSize: 0,
 	yChosenSize: 0
 });
+
+// unpackPreview fills the point lists used by the 2D and 3D previews from the packed field,
+// or keeps those of the previous message if the field did not change.
+export function unpackPreview(p: Preview, previous: Preview): Preview {
+	p.vectorFieldValues = null;
+	p.vectorFieldPositions = null;
+	p.scalarField = null;
+	if (p.unchanged) {
+		p.vectorFieldValues = previous.vectorFieldValues;
+		p.vectorFieldPositions = previous.vectorFieldPositions;
+		p.scalarField = previous.scalarField;
+		return p;
+	}
+	if (p.field == null) {
+		return p;
+	}
+	const f = decodeField(p.field);
+	if (f.nComp === 3) {
+		const values: VectorField = [];
+		const positions: VectorField = [];
+		for (let x = 0; x < f.nx; x++) {
+			for (let y = 0; y < f.ny; y++) {
+				const v = { x: fieldValue(f, 0, x, y), y: fieldValue(f, 1, x, y), z: fieldValue(f, 2, x, y) };
+				if ((v.x === 0 && v.y === 0 && v.z === 0) || isNaN(v.x)) {
+					continue;
+				}
+				positions.push({ x, y, z: 0 });
+				values.push(v);
+			}
+		}
+		p.vectorFieldValues = values;
+		p.vectorFieldPositions = positions;
+	} else {
+		const scalars: ScalarField = [];
+		for (let x = 0; x < f.nx; x++) {
+			for (let y = 0; y < f.ny; y++) {
+				const v = fieldValue(f, 0, x, y);
+				if (!isNaN(v)) {
+					scalars.push([x, y, v]);
+				}
+			}
+		}
+		p.scalarField = scalars;
+	}
+	return p;
+}
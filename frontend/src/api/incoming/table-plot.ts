@@ -31,3 +31,25 @@ export const tablePlotState = writable<TablePlot>({
     maxPoints: 0,
     step: 0,
 });
+
+export interface ColumnGroup {
+    name: string;
+    columns: string[];
+}
+
+// groupColumns groups the table columns by the part of their name before the last dot,
+// e.g. m.region3.x and m.region3.y in m.region3. Plain columns like mx form the first group.
+export function groupColumns(columns: string[]): ColumnGroup[] {
+    const groups: ColumnGroup[] = [{ name: '', columns: [] }];
+    for (const column of columns) {
+        const dot = column.lastIndexOf('.');
+        const name = dot < 0 ? '' : column.slice(0, dot);
+        let group = groups.find((g) => g.name === name);
+        if (!group) {
+            group = { name, columns: [] };
+            groups.push(group);
+        }
+        group.columns.push(column);
+    }
+    return groups.filter((g) => g.columns.length > 0);
+}
@@ -1,26 +1,31 @@
-import { previewState } from '$api/incoming/preview';
+import { previewPane, previewState } from '$api/incoming/preview';
 import { post } from '$api/post';
 import { get } from 'svelte/store';
 
 export function postComponent(component: string) {
-	post('preview/component', { component });
+	postPane('component', { component });
 }
 
 export function postQuantity(quantity: string) {
-	post('preview/quantity', { quantity });
+	postPane('quantity', { quantity });
 }
 
 export function postLayer(layer: number) {
-	post('preview/layer', { layer });
+	postPane('layer', { layer });
 }
 
 export function postXChosenSize(xChosenSize: number) {
-	post('preview/XChosenSize', { xChosenSize });
+	postPane('XChosenSize', { xChosenSize });
 }
 export function postYChosenSize(yChosenSize: number) {
-	post('preview/YChosenSize', { yChosenSize });
+	postPane('YChosenSize', { yChosenSize });
 }
 
 export function postRefresh() {
-	post('preview/refresh', {});
+	postPane('refresh', {});
+}
+
+// postPane sends a request acting on the preview pane of this tab.
+function postPane(endpoint: string, data: any) {
+	post(`preview/${endpoint}?pane=${encodeURIComponent(previewPane())}`, data);
 }
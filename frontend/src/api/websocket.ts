@@ -1,6 +1,6 @@
 import { decode } from '@msgpack/msgpack';
 
-import { type Preview, previewState } from './incoming/preview';
+import { type Preview, previewPane, previewState, unpackPreview } from './incoming/preview';
 import { type Header, headerState } from './incoming/header';
 import { type Solver, solverState } from './incoming/solver';
 import { type Console, consoleState } from './incoming/console';
@@ -20,7 +20,7 @@ export function initializeWebSocket() {
 	let ws: WebSocket | null = null;
 	
 	function connect() {
-		let wsUrl = './ws';
+		let wsUrl = './ws?pane=' + encodeURIComponent(previewPane());
 		console.debug('Connecting to WebSocket server at', wsUrl);
 		ws = new WebSocket(wsUrl);
 		ws.binaryType = 'arraybuffer';
@@ -78,7 +78,7 @@ export function parseMsgpack(data: ArrayBuffer) {
 		parameters: Parameters;
 		solver: Solver;
 		tablePlot: TablePlot;
-		preview: Preview;
+		previews: Record<string, Preview>;
 		metrics: Metrics;
 	};
 	consoleState.set(msg.console as Console);
@@ -95,7 +95,7 @@ export function parseMsgpack(data: ArrayBuffer) {
 	tablePlotState.set(msg.tablePlot as TablePlot);
 	plotTable();
 
-	previewState.set(msg.preview as Preview);
+	previewState.set(unpackPreview(msg.previews[previewPane()], get(previewState)));
 	if (get(previewState).type === '3D') {
 		preview3D();
 	} else {
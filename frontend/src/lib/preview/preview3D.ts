@@ -64,7 +64,7 @@ function createMesh(): THREE.InstancedMesh {
 	const mesh = new THREE.InstancedMesh(
 		arrowGeometry,
 		arrowMaterial,
-		get(previewState).vectorFieldValues.length
+		get(previewState).vectorFieldValues!.length
 	);
 	return mesh;
 }
@@ -118,8 +118,8 @@ function createScene() {
 }
 
 function addArrowsToMesh(mesh: THREE.InstancedMesh, scene: THREE.Scene) {
-	const vectorFieldValues = get(previewState).vectorFieldValues;
-	const vectorFieldPositions = get(previewState).vectorFieldPositions;
+	const vectorFieldValues = get(previewState).vectorFieldValues!;
+	const vectorFieldPositions = get(previewState).vectorFieldPositions!;
 
 	const dummy = new THREE.Object3D();
 
@@ -185,7 +185,7 @@ function update() {
 		const dummy = new THREE.Object3D();
 		const defaultVector = new THREE.Vector3(0, 1, 0); // Default orientation of the arrow
 		const mesh = d.mesh;
-		let vectorField = get(previewState).vectorFieldValues;
+		let vectorField = get(previewState).vectorFieldValues!;
 
 		let instanceColor = mesh.instanceColor;
 		if (!instanceColor) {
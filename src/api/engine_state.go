@@ -1,38 +1,50 @@
 package api
 
-import "github.com/labstack/echo/v4"
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/vmihailenco/msgpack/v5"
+)
 
 type EngineState struct {
-	Header    *HeaderState     `msgpack:"header"`
-	Console   *ConsoleState    `msgpack:"console"`
-	Preview   *PreviewState    `msgpack:"preview"`
-	Solver    *SolverState     `msgpack:"solver"`
-	Mesh      *MeshState       `msgpack:"mesh"`
-	Params    *ParametersState `msgpack:"parameters"`
-	TablePlot *TablePlotState  `msgpack:"tablePlot"`
-	Metrics   *MetricsState    `msgpack:"metrics"`
+	Header    *HeaderState             `msgpack:"header"`
+	Console   *ConsoleState            `msgpack:"console"`
+	Previews  map[string]*PreviewState `msgpack:"previews"` // only the panes a connection subscribed to
+	Solver    *SolverState             `msgpack:"solver"`
+	Mesh      *MeshState               `msgpack:"mesh"`
+	Params    *ParametersState         `msgpack:"parameters"`
+	TablePlot *TablePlotState          `msgpack:"tablePlot"`
+	Metrics   *MetricsState            `msgpack:"metrics"`
+
+	panes *previewPanes
 }
 
 func initEngineStateAPI(e *echo.Group, ws *WebSocketManager) *EngineState {
 	return &EngineState{
 		Header:    initHeaderAPI(),
 		Console:   initConsoleAPI(e, ws),
-		Preview:   initPreviewAPI(e, ws),
 		Solver:    initSolverAPI(e, ws),
 		Mesh:      initMeshAPI(e, ws),
 		Params:    initParameterAPI(e, ws),
 		TablePlot: initTablePlotAPI(e, ws),
 		Metrics:   initMetricsAPI(e, ws),
+		panes:     initPreviewPanesAPI(e, ws),
 	}
 }
 
 func (es *EngineState) Update() {
 	es.Header.Update()
 	es.Console.Update()
-	es.Preview.Update()
+	es.panes.Update()
 	es.Solver.Update()
 	es.Mesh.Update()
 	es.Params.Update()
 	es.TablePlot.Update()
 	es.Metrics.Update()
 }
+
+// message encodes the state for a connection subscribed to the given preview panes.
+func (es *EngineState) message(panes []string) ([]byte, error) {
+	msg := *es
+	msg.Previews = es.panes.subset(panes)
+	return msgpack.Marshal(&msg)
+}
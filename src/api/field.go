@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+)
+
+// PackedField is a compact binary encoding of a preview slice: the values of all cells and components
+// as one typed array, component by component and row by row, with x varying fastest.
+// Cells without data (outside the geometry) are NaN, or 255 for uint8.
+type PackedField struct {
+	Shape    [3]int  `msgpack:"shape"`    // [nComp, ny, nx]
+	Encoding string  `msgpack:"encoding"` // "float32", "float16" or "uint8", little endian
+	Min      float32 `msgpack:"min"`      // uint8 values q map to min + q/254*(max-min)
+	Max      float32 `msgpack:"max"`
+	Data     []byte  `msgpack:"data"`
+}
+
+var fieldEncodings = []string{"float32", "float16", "uint8"}
+
+// packField encodes f and returns it together with a hash of everything needed to decode it,
+// used to skip sending a preview that did not change.
+func packField(f *data.Slice, encoding string) (*PackedField, uint64, error) {
+	size := f.Size()
+	p := &PackedField{
+		Shape:    [3]int{f.NComp(), size[data.Y], size[data.X]},
+		Encoding: encoding,
+	}
+	p.Min, p.Max = finiteExtrema(f)
+
+	n := f.Len()
+	var raw []byte
+	switch encoding {
+	case "float32":
+		raw = make([]byte, 4*n*f.NComp())
+		for c := 0; c < f.NComp(); c++ {
+			for i, v := range f.Host()[c] {
+				binary.LittleEndian.PutUint32(raw[4*(c*n+i):], math.Float32bits(v))
+			}
+		}
+	case "float16":
+		raw = make([]byte, 2*n*f.NComp())
+		for c := 0; c < f.NComp(); c++ {
+			for i, v := range f.Host()[c] {
+				binary.LittleEndian.PutUint16(raw[2*(c*n+i):], float16Bits(v))
+			}
+		}
+	case "uint8":
+		raw = make([]byte, n*f.NComp())
+		for c := 0; c < f.NComp(); c++ {
+			for i, v := range f.Host()[c] {
+				raw[c*n+i] = quantize(v, p.Min, p.Max)
+			}
+		}
+	default:
+		return nil, 0, fmt.Errorf("unknown encoding `%s`, expected one of %v", encoding, fieldEncodings)
+	}
+
+	p.Data = raw
+
+	// the same bytes decode to other values with another scale or shape
+	h := fnv.New64a()
+	h.Write([]byte(p.Encoding))
+	_ = binary.Write(h, binary.LittleEndian, []int64{int64(p.Shape[0]), int64(p.Shape[1]), int64(p.Shape[2])})
+	_ = binary.Write(h, binary.LittleEndian, []float32{p.Min, p.Max})
+	h.Write(raw)
+	return p, h.Sum64(), nil
+}
+
+func finiteExtrema(f *data.Slice) (min, max float32) {
+	min, max = float32(math.Inf(1)), float32(math.Inf(-1))
+	for _, comp := range f.Host() {
+		for _, v := range comp {
+			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+				continue
+			}
+			min, max = float32(math.Min(float64(min), float64(v))), float32(math.Max(float64(max), float64(v)))
+		}
+	}
+	if min > max {
+		return 0, 0
+	}
+	return min, max
+}
+
+func quantize(v, min, max float32) byte {
+	if math.IsNaN(float64(v)) {
+		return 255
+	}
+	if max <= min {
+		return 0
+	}
+	q := math.Round(float64((v - min) / (max - min) * 254))
+	return byte(math.Max(0, math.Min(254, q)))
+}
+
+// float16Bits converts v to an IEEE 754 half precision float, rounding to nearest even.
+func float16Bits(v float32) uint16 {
+	b := math.Float32bits(v)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127 + 15
+	mant := b & 0x7fffff
+
+	switch {
+	case b&0x7fffffff > 0x7f800000: // NaN
+		return sign | 0x7e00
+	case exp >= 0x1f: // overflow or infinity
+		return sign | 0x7c00
+	case exp <= 0: // subnormal or zero
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		half := uint16(mant >> shift)
+		rest := mant & (1<<shift - 1)
+		if rest > 1<<(shift-1) || (rest == 1<<(shift-1) && half&1 == 1) {
+			half++
+		}
+		return sign | half
+	}
+	half := sign | uint16(exp)<<10 | uint16(mant>>13)
+	rest := mant & 0x1fff
+	if rest > 0x1000 || (rest == 0x1000 && half&1 == 1) {
+		half++ // may carry into the exponent, which is the correct rounding
+	}
+	return half
+}
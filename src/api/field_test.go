@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+)
+
+// halfToFloat decodes an IEEE 754 half precision float, like the web UI does.
+func halfToFloat(h uint16) float32 {
+	sign := float32(1)
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h >> 10 & 0x1f)
+	mant := float32(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * mant * float32(math.Pow(2, -24))
+	case 0x1f:
+		if mant != 0 {
+			return float32(math.NaN())
+		}
+		return sign * float32(math.Inf(1))
+	}
+	return sign * (1 + mant/1024) * float32(math.Pow(2, float64(exp-15)))
+}
+
+func TestFloat16Bits(t *testing.T) {
+	tests := []struct {
+		v    float32
+		want uint16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},                            // largest normal
+		{65520, 0x7c00},                            // rounds up to infinity
+		{1e6, 0x7c00},                              // overflow
+		{float32(math.Inf(1)), 0x7c00},             // +Inf
+		{float32(math.Inf(-1)), 0xfc00},            // -Inf
+		{float32(math.Pow(2, -14)), 0x0400},        // smallest normal
+		{float32(1023 * math.Pow(2, -24)), 0x03ff}, // largest subnormal
+		{float32(math.Pow(2, -24)), 0x0001},        // smallest subnormal
+		{float32(math.Pow(2, -25)), 0x0000},        // tie, rounds to even
+		{float32(3 * math.Pow(2, -25)), 0x0002},    // tie, rounds to even
+		{float32(math.Pow(2, -30)), 0x0000},        // underflow
+		{1 + 1./2048, 0x3c00},                      // tie, rounds to even
+		{1 + 3./2048, 0x3c02},                      // tie, rounds to even
+		{2 - 1./4096, 0x4000},                      // the mantissa carries into the exponent
+	}
+	for _, tt := range tests {
+		if got := float16Bits(tt.v); got != tt.want {
+			t.Errorf("float16Bits(%g) = %#04x, want %#04x", tt.v, got, tt.want)
+		}
+	}
+	if got := float16Bits(float32(math.NaN())); got&0x7c00 != 0x7c00 || got&0x3ff == 0 {
+		t.Errorf("float16Bits(NaN) = %#04x, not a NaN", got)
+	}
+}
+
+// Every half precision value converts back to itself.
+func TestFloat16RoundTrip(t *testing.T) {
+	for h := 0; h <= 0xffff; h++ {
+		v := halfToFloat(uint16(h))
+		if math.IsNaN(float64(v)) {
+			continue
+		}
+		if got := float16Bits(v); got != uint16(h) {
+			t.Errorf("float16Bits(%g) = %#04x, want %#04x", v, got, h)
+		}
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	tests := []struct {
+		v, min, max float32
+		want        byte
+	}{
+		{float32(math.NaN()), 0, 1, 255},
+		{0, 0, 1, 0},
+		{1, 0, 1, 254},
+		{0.5, 0, 1, 127},
+		{-3, -4, 0, 64},
+		{2, 0, 1, 254}, // clamped
+		{-1, 0, 1, 0},  // clamped
+		{5, 5, 5, 0},   // uniform field
+		{float32(math.Inf(1)), 0, 1, 254},
+		{float32(math.Inf(-1)), 0, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := quantize(tt.v, tt.min, tt.max); got != tt.want {
+			t.Errorf("quantize(%g, %g, %g) = %d, want %d", tt.v, tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestPackField(t *testing.T) {
+	nan, inf := float32(math.NaN()), float32(math.Inf(1))
+	f := data.NewSlice(2, [3]int{3, 2, 1})
+	copy(f.Host()[0], []float32{-1, 0, 0.5, nan, 2, inf})
+	copy(f.Host()[1], []float32{1e-6, -0.25, nan, 3, 1, -inf})
+	values := append(append([]float32{}, f.Host()[0]...), f.Host()[1]...)
+
+	for _, encoding := range fieldEncodings {
+		p, _, err := packField(f, encoding)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if p.Shape != [3]int{2, 2, 3} {
+			t.Errorf("%s: shape %v, want [2 2 3]", encoding, p.Shape)
+		}
+		if p.Min != -1 || p.Max != 3 {
+			t.Errorf("%s: extrema %g, %g, want the finite -1, 3", encoding, p.Min, p.Max)
+		}
+		for i, v := range values {
+			var got, tol float32
+			switch encoding {
+			case "float32":
+				got = math.Float32frombits(binary.LittleEndian.Uint32(p.Data[4*i:]))
+			case "float16":
+				got, tol = halfToFloat(binary.LittleEndian.Uint16(p.Data[2*i:])), 1e-3
+			case "uint8":
+				q := p.Data[i]
+				if math.IsNaN(float64(v)) {
+					if q != 255 {
+						t.Errorf("uint8: NaN at %d is %d, want 255", i, q)
+					}
+					continue
+				}
+				if q == 255 {
+					t.Errorf("uint8: value %g at %d is encoded as missing", v, i)
+					continue
+				}
+				got, tol = p.Min+float32(q)/254*(p.Max-p.Min), (p.Max-p.Min)/254/2
+				v = float32(math.Max(float64(p.Min), math.Min(float64(p.Max), float64(v))))
+			}
+			switch {
+			case math.IsNaN(float64(v)):
+				if !math.IsNaN(float64(got)) {
+					t.Errorf("%s: NaN at %d decodes to %g", encoding, i, got)
+				}
+			case math.IsInf(float64(v), 0):
+				if got != v {
+					t.Errorf("%s: %g at %d decodes to %g", encoding, v, i, got)
+				}
+			case math.Abs(float64(got-v)) > float64(tol)*math.Max(1, math.Abs(float64(v))):
+				t.Errorf("%s: %g at %d decodes to %g", encoding, v, i, got)
+			}
+		}
+	}
+
+	_, h1, _ := packField(f, "float32")
+	f.Host()[0][0] = -0.5
+	if _, h2, _ := packField(f, "float32"); h1 == h2 {
+		t.Error("the hash did not change with the values")
+	}
+
+	// uint8 bytes that stay the same while the scale changes
+	g := data.NewSlice(1, [3]int{2, 1, 1})
+	copy(g.Host()[0], []float32{0, 1})
+	p1, h1, _ := packField(g, "uint8")
+	copy(g.Host()[0], []float32{0, 2})
+	p2, h2, _ := packField(g, "uint8")
+	if string(p1.Data) != string(p2.Data) {
+		t.Fatalf("uint8 bytes %v and %v, want the same bytes for the test", p1.Data, p2.Data)
+	}
+	if h1 == h2 {
+		t.Error("the hash did not change with the scale")
+	}
+	// and the same bytes with another shape
+	h := data.NewSlice(1, [3]int{1, 2, 1})
+	copy(h.Host()[0], []float32{0, 1})
+	if _, h3, _ := packField(h, "uint8"); h3 == h1 {
+		t.Error("the hash did not change with the shape")
+	}
+
+	if _, _, err := packField(f, "int16"); err == nil {
+		t.Error("packField accepted an unknown encoding")
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/MathieuMoalic/amumax/src/cuda"
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/draw"
+	"github.com/MathieuMoalic/amumax/src/engine"
+)
+
+// default largest width or height of a rendered image, in cells
+const defaultImageMaxSize = 512
+
+// imageRequest holds the query parameters of GET /api/preview/image/:quantity
+type imageRequest struct {
+	quantity  string
+	component int // -1 for all components
+	plane     string
+	layer     int
+	maxSize   int
+	opt       draw.Options
+}
+
+func parseImageRequest(c echo.Context) (*imageRequest, error) {
+	r := &imageRequest{
+		quantity:  c.Param("quantity"),
+		component: -1,
+		plane:     "xy",
+		maxSize:   defaultImageMaxSize,
+		opt:       draw.Options{Min: "auto", Max: "auto"},
+	}
+	if _, exists := engine.Quantities[r.quantity]; !exists {
+		return nil, fmt.Errorf("quantity not found: %s", r.quantity)
+	}
+	switch comp := c.QueryParam("component"); comp {
+	case "", "3D":
+	case "x", "y", "z":
+		r.component = compStringToIndex(comp)
+	default:
+		return nil, fmt.Errorf("invalid component `%s`, expected x, y or z", comp)
+	}
+	if p := c.QueryParam("plane"); p != "" {
+		r.plane = p
+	}
+	var err error
+	if r.layer, err = intParam(c, "layer", 0); err != nil {
+		return nil, err
+	}
+	if r.maxSize, err = intParam(c, "maxsize", defaultImageMaxSize); err != nil {
+		return nil, err
+	}
+	if r.maxSize < 1 {
+		return nil, fmt.Errorf("maxsize must be positive")
+	}
+	if r.opt.ArrowSize, err = intParam(c, "arrows", 0); err != nil {
+		return nil, err
+	}
+	for _, p := range []struct {
+		name string
+		dst  *string
+	}{{"min", &r.opt.Min}, {"max", &r.opt.Max}} {
+		v := c.QueryParam(p.name)
+		if v == "" || v == "auto" {
+			continue
+		}
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return nil, fmt.Errorf("invalid %s `%s`", p.name, v)
+		}
+		*p.dst = v
+	}
+	if name := c.QueryParam("cmap"); name != "" {
+		cmap, err := draw.ColorMap(name)
+		if err != nil {
+			return nil, err
+		}
+		r.opt.ColorMap = &draw.ColorMapSpec{Cmap: cmap, Ccomp: -1}
+	}
+	r.opt.Colorbar = c.QueryParam("colorbar") == "true"
+	if c.QueryParam("labels") == "true" {
+		r.opt.Title = r.quantity
+		r.opt.ShowTime = true
+	}
+	return r, nil
+}
+
+func intParam(c echo.Context, name string, def int) (int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s `%s`", name, v)
+	}
+	return i, nil
+}
+
+// getPreviewImage renders a PNG of one layer of any quantity, independently of the web UI preview,
+// so that live images can be embedded in dashboards and notebooks.
+func getPreviewImage(c echo.Context) error {
+	r, err := parseImageRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	var (
+		host     *data.Slice
+		cellsize [3]float64
+	)
+	engine.InjectAndWait(func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("could not evaluate %s: %v", r.quantity, rec)
+			}
+		}()
+		q := engine.Quantities[r.quantity]
+		buf := engine.ValueOf(q)
+		defer cuda.Recycle(buf)
+		if r.component >= 0 && q.NComp() > 1 {
+			host = buf.Comp(r.component).HostCopy()
+		} else {
+			host = buf.HostCopy()
+		}
+		cellsize = engine.MeshOf(q).CellSize()
+		if r.opt.Title != "" {
+			r.opt.Title = engine.TitleWithUnit(r.opt.Title, q)
+		}
+		r.opt.Time = engine.Time
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+
+	slice, aspect, err := draw.Plane(host, cellsize, strings.ToLower(r.plane), r.layer)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	slice, aspect = downsampleImage(slice, aspect, r.maxSize)
+	r.opt.Aspect = aspect
+
+	var out bytes.Buffer
+	if err := draw.Render(&out, slice, ".png", r.opt); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Blob(http.StatusOK, "image/png", out.Bytes())
+}
+
+// downsampleImage averages blocks of cells of a single layer so that neither side exceeds maxSize.
+// Both sides are reduced by the same factor, the aspect ratio of a cell is corrected for rounding.
+func downsampleImage(s *data.Slice, aspect float64, maxSize int) (*data.Slice, float64) {
+	size := s.Size()
+	factor := (max(size[0], size[1]) + maxSize - 1) / maxSize
+	if factor <= 1 {
+		return s, aspect
+	}
+	n := [3]int{max(size[0]/factor, 1), max(size[1]/factor, 1), 1}
+	out := data.NewSlice(s.NComp(), n)
+	for c, comp := range data.Downsample(s.Tensors(), n) {
+		for j, row := range comp[0] {
+			copy(out.Host()[c][j*n[0]:], row)
+		}
+	}
+	sx := float64(size[0]/n[0]) / float64(size[1]/n[1])
+	if aspect == 0 {
+		aspect = 1
+	}
+	return out, aspect / sx
+}
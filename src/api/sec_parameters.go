@@ -14,6 +14,7 @@ type Field struct {
 	Value       string `msgpack:"value"`
 	Description string `msgpack:"description"`
 	Changed     bool   `msgpack:"changed"`
+	Map         string `msgpack:"map"`
 }
 
 func (f *Field) IsDefault(value string) bool {
@@ -51,6 +52,9 @@ func (s *ParametersState) getFields() {
 			Description: param.Description,
 			Changed:     engine.QuantityChanged[param.Name],
 		}
+		if param.Map != nil {
+			field.Map = param.Map()
+		}
 		fields = append(fields, field)
 	}
 	s.Fields = fields
@@ -67,7 +71,7 @@ func (s *ParametersState) postSelectParameterRegion(c echo.Context) error {
 	}
 
 	s.SelectedRegion = req.SelectedRegion
-	s.ws.engineState.Preview.Refresh = true
+	s.ws.engineState.panes.refreshAll()
 	s.ws.broadcastEngineState()
 	return c.JSON(http.StatusOK, nil)
 }
@@ -14,21 +14,23 @@ import (
 )
 
 type PreviewState struct {
-	ws                   *WebSocketManager
-	globalQuantities     []string
-	layerMask            [][]float32
-	Quantity             string               `msgpack:"quantity"`
-	Unit                 string               `msgpack:"unit"`
-	Component            string               `msgpack:"component"`
-	Layer                int                  `msgpack:"layer"`
-	Type                 string               `msgpack:"type"`
-	VectorFieldValues    []map[string]float32 `msgpack:"vectorFieldValues"`
-	VectorFieldPositions []map[string]int     `msgpack:"vectorFieldPositions"`
-	ScalarField          [][3]float32         `msgpack:"scalarField"`
-	Min                  float32              `msgpack:"min"`
-	Max                  float32              `msgpack:"max"`
-	Refresh              bool                 `msgpack:"refresh"`
-	NComp                int                  `msgpack:"nComp"`
+	ws               *WebSocketManager
+	globalQuantities []string
+	layerMask        [][]float32
+	buffer           *data.Slice  // last evaluated preview, on the host
+	lastHash         uint64       // hash of the last field sent, to skip unchanged previews
+	Quantity         string       `msgpack:"quantity"`
+	Unit             string       `msgpack:"unit"`
+	Component        string       `msgpack:"component"`
+	Layer            int          `msgpack:"layer"`
+	Type             string       `msgpack:"type"`
+	Field            *PackedField `msgpack:"field"`
+	Unchanged        bool         `msgpack:"unchanged"` // the field is omitted because it is the same as in the last message
+	Encoding         string       `msgpack:"encoding"`
+	Min              float32      `msgpack:"min"`
+	Max              float32      `msgpack:"max"`
+	Refresh          bool         `msgpack:"refresh"`
+	NComp            int          `msgpack:"nComp"`
 
 	MaxPoints       int   `msgpack:"maxPoints"`
 	DataPointsCount int   `msgpack:"dataPointsCount"`
@@ -38,37 +40,28 @@ type PreviewState struct {
 	YChosenSize     int   `msgpack:"yChosenSize"`
 }
 
-func initPreviewAPI(e *echo.Group, ws *WebSocketManager) *PreviewState {
+func newPreviewState(ws *WebSocketManager) *PreviewState {
 	previewState := &PreviewState{
-		Quantity:             "m",
-		Component:            "3D",
-		Layer:                0,
-		MaxPoints:            8192,
-		Type:                 "3D",
-		VectorFieldValues:    nil,
-		VectorFieldPositions: nil,
-		ScalarField:          nil,
-		Min:                  0,
-		Max:                  0,
-		Refresh:              true,
-		NComp:                3,
-		DataPointsCount:      0,
-		XPossibleSizes:       nil,
-		YPossibleSizes:       nil,
-		XChosenSize:          engine.Mesh.Nx,
-		YChosenSize:          engine.Mesh.Ny,
-		ws:                   ws,
-		globalQuantities:     []string{"B_demag", "B_ext", "B_eff", "Edens_demag", "Edens_ext", "Edens_eff", "geom"},
+		Quantity:         "m",
+		Component:        "3D",
+		Layer:            0,
+		MaxPoints:        8192,
+		Type:             "3D",
+		Field:            nil,
+		Encoding:         "float32",
+		Min:              0,
+		Max:              0,
+		Refresh:          true,
+		NComp:            3,
+		DataPointsCount:  0,
+		XPossibleSizes:   nil,
+		YPossibleSizes:   nil,
+		XChosenSize:      engine.Mesh.Nx,
+		YChosenSize:      engine.Mesh.Ny,
+		ws:               ws,
+		globalQuantities: []string{"B_demag", "B_ext", "B_eff", "Edens_demag", "Edens_ext", "Edens_eff", "geom"},
 	}
 	previewState.addPossibleDownscaleSizes()
-	e.POST("/api/preview/component", previewState.postPreviewComponent)
-	e.POST("/api/preview/quantity", previewState.postPreviewQuantity)
-	e.POST("/api/preview/layer", previewState.postPreviewLayer)
-	e.POST("/api/preview/maxpoints", previewState.postPreviewMaxPoints)
-	e.POST("/api/preview/refresh", previewState.postPreviewRefresh)
-	e.POST("/api/preview/XChosenSize", previewState.postXChosenSize)
-	e.POST("/api/preview/YChosenSize", previewState.postYChosenSize)
-
 	return previewState
 }
 
@@ -86,15 +79,14 @@ func (s *PreviewState) getComponent() int {
 
 func (s *PreviewState) Update() {
 	engine.InjectAndWait(s.UpdateQuantityBuffer)
+	s.packField()
 }
 
 func (s *PreviewState) UpdateQuantityBuffer() {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Log.Warn("Recovered from panic in UpdateQuantityBuffer: %v", r)
-			s.ScalarField = nil
-			s.VectorFieldPositions = nil
-			s.VectorFieldValues = nil
+			s.buffer = nil
 		}
 	}()
 	if s.layerMask == nil {
@@ -121,7 +113,7 @@ func (s *PreviewState) UpdateQuantityBuffer() {
 			data.Copy(CPUOut.Comp(c), GPUOut)
 		}
 		s.normalizeVectors(CPUOut)
-		s.UpdateVectorField(CPUOut.Vectors())
+		s.UpdateVectorField(CPUOut)
 	} else {
 		if s.getQuantity().NComp() > 1 {
 			cuda.Resize(GPUOut, GPUIn.Comp(s.getComponent()), s.Layer)
@@ -130,7 +122,7 @@ func (s *PreviewState) UpdateQuantityBuffer() {
 			cuda.Resize(GPUOut, GPUIn.Comp(0), s.Layer)
 			data.Copy(CPUOut.Comp(0), GPUOut)
 		}
-		s.UpdateScalarField(CPUOut.Scalars())
+		s.UpdateScalarField(CPUOut)
 	}
 }
 
@@ -164,79 +156,76 @@ func (s *PreviewState) normalizeVectors(f *data.Slice) {
 	}
 }
 
-func (s *PreviewState) UpdateVectorField(vectorField [3][][][]float32) {
-	// Calculate the total number of elements
-	yLen := len(vectorField[0][0])
-	xLen := len(vectorField[0][0][0])
-
-	// Create a slice to hold the array of {x, y, z} objects
-	var valArray []map[string]float32
-	var posArray []map[string]int
-	for posx := 0; posx < xLen; posx++ {
-		for posy := 0; posy < yLen; posy++ {
-			valx := vectorField[0][0][posy][posx]
-			valy := vectorField[1][0][posy][posx]
-			valz := vectorField[2][0][posy][posx]
+func (s *PreviewState) UpdateVectorField(vectorField *data.Slice) {
+	v := vectorField.Vectors()
+	count := 0
+	for posy := range v[0][0] {
+		for posx := range v[0][0][posy] {
+			valx, valy, valz := v[0][0][posy][posx], v[1][0][posy][posx], v[2][0][posy][posx]
 			if (valx == 0 && valy == 0 && valz == 0) || (math.IsNaN(float64(valx))) {
 				continue
 			}
-			posArray = append(posArray,
-				map[string]int{
-					"x": posx,
-					"y": posy,
-					"z": 0,
-				})
-			valArray = append(valArray,
-				map[string]float32{
-					"x": valx,
-					"y": valy,
-					"z": valz,
-				})
+			count++
 		}
 	}
-	s.VectorFieldPositions = posArray
-	s.VectorFieldValues = valArray
-	s.ScalarField = nil
-	s.DataPointsCount = len(valArray)
+	s.buffer = vectorField
+	s.DataPointsCount = count
 }
 
-func (s *PreviewState) UpdateScalarField(scalarField [][][]float32) {
-	xLen := len(scalarField[0][0])
-	yLen := len(scalarField[0])
-	min, max := scalarField[0][0][0], scalarField[0][0][0]
-
-	var valArray [][3]float32
-	for posx := 0; posx < xLen; posx++ {
-		for posy := 0; posy < yLen; posy++ {
+func (s *PreviewState) UpdateScalarField(scalarField *data.Slice) {
+	values := scalarField.Scalars()[0]
+	min, max := float32(math.Inf(1)), float32(math.Inf(-1))
+	count := 0
+	for posy := range values {
+		for posx, val := range values[posy] {
 			// Some quantities exist where the magnetic materials are not present
 			// and we don't want to filter them out
 			if !contains(s.globalQuantities, s.Quantity) {
 				if s.layerMask != nil {
 					if s.layerMask[posy][posx] == 0 {
+						values[posy][posx] = float32(math.NaN())
 						continue
 					}
 				}
 			}
-			val := scalarField[0][posy][posx]
 			if val < min {
 				min = val
 			}
 			if val > max {
 				max = val
 			}
-			valArray = append(valArray, [3]float32{float32(posx), float32(posy), val})
+			count++
 		}
 	}
-	if len(valArray) == 0 {
+	if count == 0 {
 		log.Log.Warn("No data in scalar field")
+		min, max = 0, 0
 	}
 
 	s.Min = min
 	s.Max = max
-	s.ScalarField = valArray
-	s.VectorFieldValues = nil
-	s.VectorFieldPositions = nil
-	s.DataPointsCount = len(valArray)
+	s.buffer = scalarField
+	s.DataPointsCount = count
+}
+
+// packField encodes the buffer for the next message. The field is left out
+// if it did not change since the last message, unless a refresh is needed.
+func (s *PreviewState) packField() {
+	s.Field, s.Unchanged = nil, false
+	if s.buffer == nil {
+		s.lastHash = 0
+		return
+	}
+	field, hash, err := packField(s.buffer, s.Encoding)
+	if err != nil {
+		log.Log.Err("Could not encode the preview: %v", err)
+		return
+	}
+	if hash == s.lastHash && !s.Refresh {
+		s.Unchanged = true
+		return
+	}
+	s.Field, s.lastHash = field, hash
 }
 
 func (s *PreviewState) updateMask() {
@@ -504,3 +493,21 @@ func (s *PreviewState) postYChosenSize(c echo.Context) error {
 	s.ws.broadcastEngineState()
 	return c.JSON(http.StatusOK, nil)
 }
+
+func (s *PreviewState) postPreviewEncoding(c echo.Context) error {
+	type Request struct {
+		Encoding string `msgpack:"encoding"`
+	}
+	req := new(Request)
+	if err := c.Bind(req); err != nil {
+		log.Log.Err("%v", err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid request payload"})
+	}
+	if !contains(fieldEncodings, req.Encoding) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid encoding"})
+	}
+	s.Encoding = req.Encoding
+	s.Refresh = true
+	s.ws.broadcastEngineState()
+	return c.JSON(http.StatusOK, nil)
+}
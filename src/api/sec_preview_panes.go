@@ -0,0 +1,154 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultPane is the preview pane used when a client does not name one.
+const defaultPane = "main"
+
+// previewPanes holds independent preview panes by name, each with its own quantity, component, layer
+// and downsampling. Websocket connections subscribe to panes and only receive those, and the
+// preview endpoints act on the pane given by the `pane` query parameter. A pane exists from its
+// first subscriber to its last one, except the default pane which always exists.
+type previewPanes struct {
+	ws          *WebSocketManager
+	mu          sync.Mutex
+	panes       map[string]*PreviewState
+	subscribers map[string]int
+}
+
+func initPreviewPanesAPI(e *echo.Group, ws *WebSocketManager) *previewPanes {
+	p := &previewPanes{
+		ws:          ws,
+		panes:       make(map[string]*PreviewState),
+		subscribers: make(map[string]int),
+	}
+	p.get(defaultPane)
+	e.POST("/api/preview/component", p.handle((*PreviewState).postPreviewComponent))
+	e.POST("/api/preview/quantity", p.handle((*PreviewState).postPreviewQuantity))
+	e.POST("/api/preview/layer", p.handle((*PreviewState).postPreviewLayer))
+	e.POST("/api/preview/maxpoints", p.handle((*PreviewState).postPreviewMaxPoints))
+	e.POST("/api/preview/refresh", p.handle((*PreviewState).postPreviewRefresh))
+	e.POST("/api/preview/XChosenSize", p.handle((*PreviewState).postXChosenSize))
+	e.POST("/api/preview/YChosenSize", p.handle((*PreviewState).postYChosenSize))
+	e.POST("/api/preview/encoding", p.handle((*PreviewState).postPreviewEncoding))
+	e.GET("/api/preview/image/:quantity", getPreviewImage)
+	return p
+}
+
+// get returns the pane called name, creating it with the default settings if needed.
+func (p *previewPanes) get(name string) *PreviewState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, exists := p.panes[name]
+	if !exists {
+		s = newPreviewState(p.ws)
+		p.panes[name] = s
+	}
+	return s
+}
+
+// lookup returns the pane called name, if it exists.
+func (p *previewPanes) lookup(name string) (*PreviewState, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, exists := p.panes[name]
+	return s, exists
+}
+
+func (p *previewPanes) handle(h func(*PreviewState, echo.Context) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		name := c.QueryParam("pane")
+		if name == "" {
+			name = defaultPane
+		}
+		if !validPaneName(name) {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid pane name"})
+		}
+		s, exists := p.lookup(name)
+		if !exists {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "Unknown pane, subscribe to it first"})
+		}
+		return h(s, c)
+	}
+}
+
+func validPaneName(name string) bool {
+	return name != "" && len(name) <= 64
+}
+
+// subscribe registers a connection to the given panes, which are sent in full in the next message.
+func (p *previewPanes) subscribe(names []string) {
+	for _, name := range names {
+		s := p.get(name)
+		p.mu.Lock()
+		p.subscribers[name]++
+		s.Refresh = true
+		p.mu.Unlock()
+	}
+}
+
+// unsubscribe removes a connection from the given panes. Panes without subscribers are deleted,
+// the default pane keeps its settings but is no longer evaluated.
+func (p *previewPanes) unsubscribe(names []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, name := range names {
+		p.subscribers[name]--
+		if p.subscribers[name] > 0 {
+			continue
+		}
+		delete(p.subscribers, name)
+		if name != defaultPane {
+			delete(p.panes, name)
+			continue
+		}
+		s := p.panes[name]
+		s.buffer, s.Field, s.lastHash = nil, nil, 0
+	}
+}
+
+// active returns the panes that have at least one subscriber.
+func (p *previewPanes) active() []*PreviewState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var active []*PreviewState
+	for name := range p.subscribers {
+		active = append(active, p.panes[name])
+	}
+	return active
+}
+
+func (p *previewPanes) Update() {
+	for _, s := range p.active() {
+		s.Update()
+	}
+}
+
+// subset returns the panes of a message for a connection subscribed to names.
+func (p *previewPanes) subset(names []string) map[string]*PreviewState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]*PreviewState, len(names))
+	for _, name := range names {
+		out[name] = p.panes[name]
+	}
+	return out
+}
+
+// refreshAll makes every pane send its field in the next message.
+func (p *previewPanes) refreshAll() {
+	for _, s := range p.active() {
+		s.Refresh = true
+	}
+}
+
+func (p *previewPanes) clearRefresh() {
+	for _, s := range p.active() {
+		s.Refresh = false
+	}
+}
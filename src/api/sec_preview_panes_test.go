@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/MathieuMoalic/amumax/src/engine"
+)
+
+func TestPreviewPanesLifecycle(t *testing.T) {
+	// new panes wait for the mesh
+	nx, ny := engine.Mesh.Nx, engine.Mesh.Ny
+	engine.Mesh.Nx, engine.Mesh.Ny = 8, 4
+	defer func() { engine.Mesh.Nx, engine.Mesh.Ny = nx, ny }()
+
+	p := &previewPanes{
+		panes:       make(map[string]*PreviewState),
+		subscribers: make(map[string]int),
+	}
+	p.get(defaultPane)
+
+	post := func(pane string) int {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/preview/refresh?pane="+pane, nil), rec)
+		err := p.handle(func(*PreviewState, echo.Context) error { return c.NoContent(http.StatusOK) })(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code
+	}
+
+	if code := post("tab-a"); code != http.StatusNotFound {
+		t.Errorf("POST to a pane nobody subscribed to: %d, want %d", code, http.StatusNotFound)
+	}
+	if _, exists := p.lookup("tab-a"); exists {
+		t.Error("a POST created the pane")
+	}
+
+	p.subscribe([]string{"tab-a", defaultPane})
+	p.subscribe([]string{"tab-a"})
+	if code := post("tab-a"); code != http.StatusOK {
+		t.Errorf("POST to a subscribed pane: %d, want %d", code, http.StatusOK)
+	}
+
+	p.unsubscribe([]string{"tab-a", defaultPane})
+	if _, exists := p.lookup("tab-a"); !exists {
+		t.Error("the pane was deleted while it still has a subscriber")
+	}
+	p.unsubscribe([]string{"tab-a"})
+	if _, exists := p.lookup("tab-a"); exists {
+		t.Error("the pane was kept after its last subscriber left")
+	}
+	if _, exists := p.lookup(defaultPane); !exists {
+		t.Error("the default pane was deleted")
+	}
+	if len(p.active()) != 0 {
+		t.Errorf("%d active panes without subscribers", len(p.active()))
+	}
+}
@@ -194,7 +194,7 @@ func (t *TablePlotState) postTablePlotMaxPoints(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid request payload"})
 	}
 	t.MaxPoints = req.MaxPoints
-	t.ws.engineState.Preview.Refresh = true
+	t.ws.engineState.panes.refreshAll()
 	t.ws.broadcastEngineState()
 	return c.JSON(http.StatusOK, nil)
 }
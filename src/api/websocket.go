@@ -2,12 +2,12 @@ package api
 
 import (
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
-	"github.com/vmihailenco/msgpack/v5"
 
 	"github.com/MathieuMoalic/amumax/src/engine"
 	"github.com/MathieuMoalic/amumax/src/log"
@@ -24,7 +24,7 @@ type WebSocketManager struct {
 }
 
 type connectionManager struct {
-	conns map[*websocket.Conn]struct{}
+	conns map[*websocket.Conn][]string // preview panes of each connection
 	mu    sync.Mutex
 }
 
@@ -36,18 +36,18 @@ func newWebSocketManager() *WebSocketManager {
 			},
 		},
 		connections: &connectionManager{
-			conns: make(map[*websocket.Conn]struct{}),
+			conns: make(map[*websocket.Conn][]string),
 			mu:    sync.Mutex{},
 		},
 		broadcastStop: make(chan struct{}),
 	}
 }
 
-func (cm *connectionManager) add(ws *websocket.Conn) {
+func (cm *connectionManager) add(ws *websocket.Conn, panes []string) {
 	log.Log.Debug("Websocket connection added")
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
-	cm.conns[ws] = struct{}{}
+	cm.conns[ws] = panes
 }
 
 func (cm *connectionManager) remove(ws *websocket.Conn) {
@@ -57,10 +57,15 @@ func (cm *connectionManager) remove(ws *websocket.Conn) {
 	delete(cm.conns, ws)
 }
 
-func (cm *connectionManager) broadcast(msg []byte) {
+// broadcast sends to every connection the message for its preview panes.
+func (cm *connectionManager) broadcast(message func(panes []string) []byte) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
-	for ws := range cm.conns {
+	for ws, panes := range cm.conns {
+		msg := message(panes)
+		if msg == nil {
+			continue
+		}
 		err := ws.WriteMessage(websocket.BinaryMessage, msg)
 		if err != nil {
 			log.Log.Err("Error sending message via WebSocket: %v", err)
@@ -86,9 +91,11 @@ func (wsManager *WebSocketManager) websocketEntrypoint(c echo.Context) error {
 		}
 	}()
 
-	wsManager.connections.add(ws)
+	panes := subscribedPanes(c)
+	wsManager.engineState.panes.subscribe(panes)
+	defer wsManager.engineState.panes.unsubscribe(panes)
+	wsManager.connections.add(ws, panes)
 	defer wsManager.connections.remove(ws)
-	wsManager.engineState.Preview.Refresh = true
 	wsManager.broadcastEngineState()
 
 	// Channel to signal when to stop the goroutine
@@ -113,16 +120,39 @@ func (wsManager *WebSocketManager) websocketEntrypoint(c echo.Context) error {
 	}
 }
 
+// subscribedPanes returns the preview panes requested with `pane` query parameters, or the default one.
+func subscribedPanes(c echo.Context) []string {
+	var panes []string
+	for _, name := range c.QueryParams()["pane"] {
+		if validPaneName(name) && !contains(panes, name) {
+			panes = append(panes, name)
+		}
+	}
+	if len(panes) == 0 {
+		panes = []string{defaultPane}
+	}
+	return panes
+}
+
 func (wsManager *WebSocketManager) broadcastEngineState() {
 	wsManager.engineState.Update()
-	msg, err := msgpack.Marshal(wsManager.engineState)
-	if err != nil {
-		log.Log.Err("Error marshaling combined message: %v", err)
-		return
-	}
-	wsManager.connections.broadcast(msg)
-	// Reset the refresh flag
-	wsManager.engineState.Preview.Refresh = false
+	// connections subscribed to the same panes share the message
+	messages := make(map[string][]byte)
+	wsManager.connections.broadcast(func(panes []string) []byte {
+		key := strings.Join(panes, "\x00")
+		if msg, ok := messages[key]; ok {
+			return msg
+		}
+		msg, err := wsManager.engineState.message(panes)
+		if err != nil {
+			log.Log.Err("Error marshaling combined message: %v", err)
+			return nil
+		}
+		messages[key] = msg
+		return msg
+	})
+	// Reset the refresh flags
+	wsManager.engineState.panes.clearRefresh()
 }
 
 func (wsManager *WebSocketManager) startBroadcastLoop() {
@@ -10,6 +10,7 @@ import (
 
 	"github.com/MathieuMoalic/amumax/src/entrypoint"
 	"github.com/MathieuMoalic/amumax/src/flags"
+	"github.com/MathieuMoalic/amumax/src/render"
 	"github.com/MathieuMoalic/amumax/src/template"
 	"github.com/MathieuMoalic/amumax/src/version"
 )
@@ -40,6 +41,19 @@ func Entrypoint() {
 	templateFlags.ParseFlags(templateCmd)
 	rootCmd.AddCommand(templateCmd)
 
+	// Define the render subcommand
+	renderFlags := &flags.RenderFlags{}
+	renderCmd := &cobra.Command{
+		Use:   "render [dataset path]",
+		Short: "Render a saved quantity, e.g. sim.zarr/m, as an animated GIF or APNG",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			renderEntrypoint(args[0], renderFlags)
+		},
+	}
+	renderFlags.ParseFlags(renderCmd)
+	rootCmd.AddCommand(renderCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -58,3 +72,12 @@ func templateEntrypoint(templatePath string, flags *flags.TemplateFlags) {
 	}
 	color.Green("Template processed successfully")
 }
+
+func renderEntrypoint(dataset string, flags *flags.RenderFlags) {
+	err := render.Render(dataset, flags)
+	if err != nil {
+		color.Red(fmt.Sprintf("Error rendering %s: %v", dataset, err))
+		os.Exit(1)
+	}
+	color.Green("Rendering done")
+}
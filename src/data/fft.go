@@ -0,0 +1,95 @@
+package data
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// FFT returns the discrete Fourier transform X[k] = Σ x[n] exp(-2πi kn/N) of x, for any length N.
+// Powers of two use a radix-2 transform, other lengths Bluestein's algorithm.
+func FFT(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	copy(out, x)
+	if n <= 1 {
+		return out
+	}
+	if n&(n-1) == 0 {
+		fftRadix2(out)
+		return out
+	}
+	return bluestein(out)
+}
+
+// FFTFreq returns the frequencies of the FFT bins of n samples spaced by d, in cycles per unit of d.
+func FFTFreq(n int, d float64) []float64 {
+	f := make([]float64, n)
+	for i := range f {
+		k := i
+		if i > (n-1)/2 {
+			k = i - n
+		}
+		f[i] = float64(k) / (float64(n) * d)
+	}
+	return f
+}
+
+// fftRadix2 transforms x in place, len(x) must be a power of two.
+func fftRadix2(x []complex128) {
+	n := len(x)
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range x {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], wk*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				wk *= w
+			}
+		}
+	}
+}
+
+// bluestein writes the DFT as a convolution, evaluated with power of two transforms.
+func bluestein(x []complex128) []complex128 {
+	n := len(x)
+	m := 1
+	for m < 2*n-1 {
+		m <<= 1
+	}
+	chirp := make([]complex128, n)
+	for k := range chirp {
+		// k² mod 2n keeps the argument small for large n
+		kk := (k * k) % (2 * n)
+		chirp[k] = cmplx.Exp(complex(0, -math.Pi*float64(kk)/float64(n)))
+	}
+	a := make([]complex128, m)
+	b := make([]complex128, m)
+	for k := range x {
+		a[k] = x[k] * chirp[k]
+	}
+	b[0] = cmplx.Conj(chirp[0])
+	for k := 1; k < n; k++ {
+		b[k] = cmplx.Conj(chirp[k])
+		b[m-k] = b[k]
+	}
+	fftRadix2(a)
+	fftRadix2(b)
+	for i := range a {
+		a[i] = cmplx.Conj(a[i] * b[i])
+	}
+	fftRadix2(a) // inverse transform through conjugation
+	out := make([]complex128, n)
+	for k := range out {
+		out[k] = cmplx.Conj(a[k]) / complex(float64(m), 0) * chirp[k]
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func naiveDFT(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	for k := range out {
+		for j, v := range x {
+			out[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(k*j)/float64(n)))
+		}
+	}
+	return out
+}
+
+func TestFFT(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 8, 12, 64, 100} {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(math.Sin(float64(i)*0.7)+0.1*float64(i), math.Cos(float64(i*i)))
+		}
+		want := naiveDFT(x)
+		got := FFT(x)
+		for k := range want {
+			if cmplx.Abs(got[k]-want[k]) > 1e-9*float64(n) {
+				t.Errorf("n=%d: X[%d] = %v, want %v", n, k, got[k], want[k])
+			}
+		}
+	}
+}
+
+func TestFFTFreq(t *testing.T) {
+	want := []float64{0, 0.25, -0.5, -0.25}
+	got := FFTFreq(4, 1)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("FFTFreq(4, 1)[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	want = []float64{0, 0.2, 0.4, -0.4, -0.2}
+	got = FFTFreq(5, 1)
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-15 {
+			t.Errorf("FFTFreq(5, 1)[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
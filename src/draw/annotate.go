@@ -0,0 +1,158 @@
+package draw
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+)
+
+// Options controls how Render draws a slice.
+type Options struct {
+	Min, Max  string // "auto" or a number
+	ArrowSize int
+	ColorMap  *ColorMapSpec // nil: black-white scalars and HSL vectors
+	Colorbar  bool          // add a colorbar with min/max labels, for scalar images
+	Title     string        // e.g. quantity name and unit, written on the top left
+	Time      float64       // written on the top right if ShowTime is set
+	ShowTime  bool
+	Aspect    float64 // height/width ratio of a cell, the image is stretched accordingly. 0 means 1.
+}
+
+func (o Options) annotated() bool {
+	return o.Colorbar || o.Title != "" || o.ShowTime
+}
+
+// annotated images are upscaled to at least this height so that labels stay readable
+const minAnnotatedHeight = 256
+
+var face = basicfont.Face7x13
+
+func createAnnotatedImage(f *data.Slice, opt Options) *image.RGBA {
+	var colormap []ColorMapSpec
+	if opt.ColorMap != nil {
+		colormap = []ColorMapSpec{*opt.ColorMap}
+	}
+	img := stretch(createRGBAImage(f, opt.Min, opt.Max, opt.ArrowSize, colormap...), opt.Aspect)
+	if !opt.annotated() {
+		return img
+	}
+	img = upscale(img, (minAnnotatedHeight+img.Bounds().Dy()-1)/img.Bounds().Dy())
+
+	scalar := f
+	if f.NComp() == 3 {
+		scalar = nil
+		if opt.ColorMap != nil && opt.ColorMap.Ccomp >= 0 {
+			scalar = f.Comp(opt.ColorMap.Ccomp)
+		}
+	}
+	if opt.Colorbar && scalar != nil {
+		min, max := parseMinMax(scalar, opt.Min, opt.Max)
+		var stops []color.RGBA
+		if opt.ColorMap != nil {
+			stops = opt.ColorMap.Cmap
+		}
+		img = addColorbar(img, min, max, stops)
+	}
+	if opt.Title != "" {
+		drawLabel(img, opt.Title, 4, 4, false)
+	}
+	if opt.ShowTime {
+		drawLabel(img, "t = "+siFormat(opt.Time, "s"), imageDataWidth(img, opt, scalar != nil)-4, 4, true)
+	}
+	return img
+}
+
+// imageDataWidth is the width of the data part of an annotated image, without the colorbar.
+func imageDataWidth(img *image.RGBA, opt Options, scalar bool) int {
+	if opt.Colorbar && scalar {
+		return img.Bounds().Dx() - colorbarWidth
+	}
+	return img.Bounds().Dx()
+}
+
+// upscale enlarges img by an integer factor with nearest neighbor interpolation.
+func upscale(img *image.RGBA, factor int) *image.RGBA {
+	if factor <= 1 {
+		return img
+	}
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx()*factor, b.Dy()*factor))
+	for y := 0; y < out.Bounds().Dy(); y++ {
+		for x := 0; x < out.Bounds().Dx(); x++ {
+			out.SetRGBA(x, y, img.RGBAAt(b.Min.X+x/factor, b.Min.Y+y/factor))
+		}
+	}
+	return out
+}
+
+const (
+	colorbarWidth  = 90 // total width added to the image
+	colorbarMargin = 8
+	colorbarBar    = 16 // width of the color strip
+)
+
+// addColorbar returns img with a vertical colorbar from min (bottom) to max (top) on its right.
+func addColorbar(img *image.RGBA, min, max float32, colormap []color.RGBA) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	out := image.NewRGBA(image.Rect(0, 0, w+colorbarWidth, h))
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(out, img.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	x0 := w + colorbarMargin
+	top, bottom := colorbarMargin, h-colorbarMargin
+	for y := top; y < bottom; y++ {
+		v := max - (max-min)*float32(y-top)/float32(bottom-top-1)
+		c := colorMap(min, max, v, colormap...)
+		for x := x0; x < x0+colorbarBar; x++ {
+			out.SetRGBA(x, y, c)
+		}
+	}
+	labelX := x0 + colorbarBar + 4
+	drawText(out, fmt.Sprintf("%.3g", max), labelX, top+face.Ascent)
+	drawText(out, fmt.Sprintf("%.3g", (min+max)/2), labelX, (top+bottom)/2+face.Ascent/2)
+	drawText(out, fmt.Sprintf("%.3g", min), labelX, bottom)
+	return out
+}
+
+// drawLabel writes text on a translucent white box with its top corner at (x, y),
+// the left corner or the right one if alignRight is set.
+func drawLabel(img *image.RGBA, text string, x, y int, alignRight bool) {
+	width := font.MeasureString(face, text).Ceil()
+	if alignRight {
+		x -= width + 4
+	}
+	box := image.Rect(x, y, x+width+4, y+face.Height+2)
+	draw.Draw(img, box, image.NewUniform(color.NRGBA{255, 255, 255, 180}), image.Point{}, draw.Over)
+	drawText(img, text, x+2, y+1+face.Ascent)
+}
+
+// drawText writes black text with its baseline starting at (x, y).
+func drawText(img *image.RGBA, text string, x, y int) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// siFormat formats v with an SI prefix, e.g. 1.5e-9 s as "1.5 ns".
+func siFormat(v float64, unit string) string {
+	prefixes := []string{"f", "p", "n", "µ", "m", "", "k", "M", "G"}
+	if v == 0 {
+		return "0 " + unit
+	}
+	// index in prefixes, 5 being no prefix
+	i := int(math.Floor(math.Log10(math.Abs(v))/3)) + 5
+	i = max(0, min(len(prefixes)-1, i))
+	return fmt.Sprintf("%.4g %s%s", v/math.Pow(10, float64(3*(i-5))), prefixes[i], unit)
+}
@@ -0,0 +1,74 @@
+package draw
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Named colormaps, sampled at evenly spaced stops and linearly interpolated by colorMap.
+var colormaps = map[string][]color.RGBA{
+	"gray": hexStops("#000000", "#ffffff"),
+	"viridis": hexStops("#440154", "#482475", "#414487", "#355f8d", "#2a788e", "#21918c",
+		"#22a884", "#44bf70", "#7ad151", "#bddf26", "#fde725"),
+	"magma": hexStops("#000004", "#140e36", "#3b0f70", "#641a80", "#8c2981", "#b73779",
+		"#de4968", "#f7705c", "#fe9f6d", "#fecf92", "#fcfdbf"),
+	// diverging, for signed quantities with a symmetric scale
+	"RdBu": hexStops("#67001f", "#b2182b", "#d6604d", "#f4a582", "#fddbc7", "#f7f7f7",
+		"#d1e5f0", "#92c5de", "#4393c3", "#2166ac", "#053061"),
+	// cyclic, for angles: both ends have the same color
+	"twilight": hexStops("#e2d9e2", "#a9c3cd", "#7b9cc3", "#6672b6", "#5d45a0", "#2f1436",
+		"#6b1f4b", "#a1384c", "#c3664f", "#d39d83", "#e2d9e2"),
+}
+
+func hexStops(hex ...string) []color.RGBA {
+	stops := make([]color.RGBA, len(hex))
+	for i, h := range hex {
+		v, err := strconv.ParseUint(strings.TrimPrefix(h, "#"), 16, 32)
+		if err != nil {
+			panic(err)
+		}
+		stops[i] = color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}
+	}
+	return stops
+}
+
+// ColorMap returns the stops of a named colormap. Names are case-insensitive,
+// a "_r" suffix reverses the colormap.
+func ColorMap(name string) ([]color.RGBA, error) {
+	base, reversed := strings.CutSuffix(name, "_r")
+	for n, stops := range colormaps {
+		if !strings.EqualFold(n, base) {
+			continue
+		}
+		out := append([]color.RGBA(nil), stops...)
+		if reversed {
+			for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+				out[i], out[j] = out[j], out[i]
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown colormap `%s`, expected one of %v", name, ColorMaps())
+}
+
+// ColorMaps returns the names of the registered colormaps.
+func ColorMaps() []string {
+	names := make([]string, 0, len(colormaps))
+	for n := range colormaps {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterColorMap adds or replaces a named colormap.
+func RegisterColorMap(name string, stops []color.RGBA) error {
+	if len(stops) < 2 {
+		return fmt.Errorf("colormap `%s` needs at least 2 colors, got %d", name, len(stops))
+	}
+	colormaps[name] = stops
+	return nil
+}
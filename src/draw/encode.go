@@ -15,29 +15,50 @@ import (
 )
 
 func RenderFormat(out io.Writer, f *data.Slice, min, max string, arrowSize int, format string, colormap ...ColorMapSpec) error {
-	codecs := map[string]codec{".png": pngfull, ".jpg": jpeg100, ".gif": gif256}
-	ext := strings.ToLower(path.Ext(format))
-	enc := codecs[ext]
-	if enc == nil {
-		return fmt.Errorf("render: unhandled image type: %s", ext)
+	opt := Options{Min: min, Max: max, ArrowSize: arrowSize}
+	if len(colormap) > 0 {
+		opt.ColorMap = &colormap[0]
 	}
-	return render(out, f, min, max, arrowSize, enc, colormap...)
+	return Render(out, f, format, opt)
 }
 
-// encodes an image
-type codec func(io.Writer, image.Image) error
-
-// render data and encode with arbitrary codec.
-func render(out io.Writer, f *data.Slice, min, max string, arrowSize int, encode codec, colormap ...ColorMapSpec) error {
-	img := createRGBAImage(f, min, max, arrowSize, colormap...)
+// Render encodes f as an image of the type given by the extension of format, with colorbar and labels if requested.
+// The lossless formats ".png16" (16-bit grayscale png of a scalar, scaled from Min to Max) and ".tif"/".tiff"
+// (raw float32 values) ignore the colormap and annotations.
+func Render(out io.Writer, f *data.Slice, format string, opt Options) error {
+	ext := strings.ToLower(path.Ext(format))
 	buf := bufio.NewWriter(out)
-	err := encode(buf, img)
+	var err error
+	switch ext {
+	case ".png16":
+		err = encodeGray16(buf, f, opt.Min, opt.Max)
+	case ".tif", ".tiff":
+		err = encodeTIFF(buf, f)
+	default:
+		return renderImage(buf, f, ext, opt)
+	}
 	if err != nil {
 		return err
 	}
 	return buf.Flush()
 }
 
+func renderImage(buf *bufio.Writer, f *data.Slice, ext string, opt Options) error {
+	codecs := map[string]codec{".png": pngfull, ".jpg": jpeg100, ".gif": gif256}
+	enc := codecs[ext]
+	if enc == nil {
+		return fmt.Errorf("render: unhandled image type: %s", ext)
+	}
+	img := createAnnotatedImage(f, opt)
+	if err := enc(buf, img); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// encodes an image
+type codec func(io.Writer, image.Image) error
+
 // full-quality jpeg codec, passable to Render()
 func jpeg100(w io.Writer, img image.Image) error {
 	return jpeg.Encode(w, img, &jpeg.Options{Quality: 100})
@@ -0,0 +1,181 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+)
+
+// Image renders f with the given options.
+func Image(f *data.Slice, opt Options) *image.RGBA {
+	return createAnnotatedImage(f, opt)
+}
+
+// MovieWriter encodes frames of the same size as an animation.
+type MovieWriter interface {
+	WriteFrame(img image.Image) error
+	Close() error // writes what remains of the file, does not close the underlying writer
+}
+
+// NewMovieWriter returns an animated GIF or APNG (.png) encoder of nframes frames, depending on the extension of format.
+func NewMovieWriter(w io.Writer, format string, nframes int, delay time.Duration) (MovieWriter, error) {
+	switch strings.ToLower(path.Ext(format)) {
+	case ".gif":
+		return &gifWriter{w: w, delay: int(delay / (10 * time.Millisecond))}, nil
+	case ".png", ".apng":
+		return &apngWriter{w: w, nframes: nframes, delay: delay}, nil
+	default:
+		return nil, fmt.Errorf("render: unhandled animation type: %s, expected .gif or .png", path.Ext(format))
+	}
+}
+
+// gifWriter keeps the paletted frames in memory, GIF needs them all before encoding.
+type gifWriter struct {
+	w     io.Writer
+	delay int // 1/100 s
+	anim  gif.GIF
+}
+
+func (g *gifWriter) WriteFrame(img image.Image) error {
+	p := image.NewPaletted(img.Bounds(), palette.Plan9)
+	draw.FloydSteinberg.Draw(p, img.Bounds(), img, img.Bounds().Min)
+	g.anim.Image = append(g.anim.Image, p)
+	g.anim.Delay = append(g.anim.Delay, g.delay)
+	return nil
+}
+
+func (g *gifWriter) Close() error {
+	if len(g.anim.Image) == 0 {
+		return fmt.Errorf("render: no frames to encode")
+	}
+	return gif.EncodeAll(g.w, &g.anim)
+}
+
+// apngWriter streams an animated PNG: every frame is PNG-encoded and its image data
+// is moved into the fcTL/fdAT chunks of the APNG extension.
+type apngWriter struct {
+	w       io.Writer
+	nframes int
+	delay   time.Duration
+	frame   int
+	seq     uint32 // sequence number of the animation chunks
+	err     error
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func (a *apngWriter) WriteFrame(img image.Image) error {
+	if a.frame >= a.nframes {
+		return fmt.Errorf("render: more frames than the %d announced", a.nframes)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	chunks, err := pngChunks(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if a.frame == 0 {
+		a.write(pngSignature)
+		for _, c := range chunks {
+			if c.typ == "IHDR" {
+				a.chunk("IHDR", c.data)
+			}
+		}
+		a.chunk("acTL", be32(uint32(a.nframes), 0)) // 0: loop forever
+	}
+	b := img.Bounds()
+	// delay as a fraction of seconds, in ms
+	fctl := append(be32(a.seq, uint32(b.Dx()), uint32(b.Dy()), 0, 0), be16(uint16(a.delay.Milliseconds()), 1000)...)
+	a.chunk("fcTL", append(fctl, 0, 0)) // dispose: none, blend: source
+	a.seq++
+	for _, c := range chunks {
+		if c.typ != "IDAT" {
+			continue
+		}
+		if a.frame == 0 {
+			a.chunk("IDAT", c.data)
+		} else {
+			a.chunk("fdAT", append(be32(a.seq), c.data...))
+			a.seq++
+		}
+	}
+	a.frame++
+	return a.err
+}
+
+func (a *apngWriter) Close() error {
+	if a.frame != a.nframes {
+		return fmt.Errorf("render: %d frames written, %d announced", a.frame, a.nframes)
+	}
+	a.chunk("IEND", nil)
+	return a.err
+}
+
+func (a *apngWriter) write(b []byte) {
+	if a.err == nil {
+		_, a.err = a.w.Write(b)
+	}
+}
+
+func (a *apngWriter) chunk(typ string, data []byte) {
+	a.write(be32(uint32(len(data))))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	a.write([]byte(typ))
+	a.write(data)
+	a.write(be32(crc.Sum32()))
+}
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// pngChunks splits an encoded PNG into its chunks.
+func pngChunks(b []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(b, pngSignature) {
+		return nil, fmt.Errorf("render: invalid png signature")
+	}
+	b = b[len(pngSignature):]
+	var chunks []pngChunk
+	for len(b) >= 12 {
+		n := int(binary.BigEndian.Uint32(b))
+		if len(b) < 12+n {
+			return nil, fmt.Errorf("render: truncated png chunk")
+		}
+		chunks = append(chunks, pngChunk{string(b[4:8]), b[8 : 8+n]})
+		b = b[12+n:]
+	}
+	return chunks, nil
+}
+
+func be32(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+func be16(values ...uint16) []byte {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(b[2*i:], v)
+	}
+	return b
+}
@@ -0,0 +1,120 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAPNGWriter(t *testing.T) {
+	const w, h = 4, 3
+	frames := make([]*image.RGBA, 2)
+	for i := range frames {
+		frames[i] = image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				frames[i].Set(x, y, color.RGBA{uint8(60 * x), uint8(80 * y), uint8(200 * i), 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	mw, err := NewMovieWriter(&buf, "movie.png", len(frames), 40*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range frames {
+		if err := mw.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.WriteFrame(frames[0]); err == nil {
+		t.Error("wrote more frames than announced")
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the chunks and their order: IHDR acTL fcTL IDAT... fcTL fdAT... IEND
+	b := buf.Bytes()
+	chunks, err := pngChunks(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rest := b[len(pngSignature):]; len(rest) >= 12; {
+		n := int(binary.BigEndian.Uint32(rest))
+		if crc := crc32.ChecksumIEEE(rest[4 : 8+n]); binary.BigEndian.Uint32(rest[8+n:]) != crc {
+			t.Errorf("chunk %s: wrong CRC", rest[4:8])
+		}
+		rest = rest[12+n:]
+	}
+	var layout []string
+	var seq []uint32
+	for _, c := range chunks {
+		if len(layout) == 0 || layout[len(layout)-1] != c.typ {
+			layout = append(layout, c.typ)
+		}
+		switch c.typ {
+		case "acTL":
+			if frames, plays := binary.BigEndian.Uint32(c.data), binary.BigEndian.Uint32(c.data[4:]); frames != 2 || plays != 0 {
+				t.Errorf("acTL: %d frames played %d times, want 2 frames looping", frames, plays)
+			}
+		case "fcTL":
+			seq = append(seq, binary.BigEndian.Uint32(c.data))
+			d := c.data
+			if width, height := binary.BigEndian.Uint32(d[4:]), binary.BigEndian.Uint32(d[8:]); width != w || height != h {
+				t.Errorf("fcTL: frame of %dx%d, want %dx%d", width, height, w, h)
+			}
+			if num, den := binary.BigEndian.Uint16(d[20:]), binary.BigEndian.Uint16(d[22:]); num != 40 || den != 1000 {
+				t.Errorf("fcTL: delay %d/%d s, want 40/1000", num, den)
+			}
+		case "fdAT":
+			seq = append(seq, binary.BigEndian.Uint32(c.data))
+		}
+	}
+	want := []string{"IHDR", "acTL", "fcTL", "IDAT", "fcTL", "fdAT", "IEND"}
+	if !slices.Equal(layout, want) {
+		t.Fatalf("chunks %v, want %v", layout, want)
+	}
+	for i, s := range seq {
+		if s != uint32(i) {
+			t.Errorf("sequence numbers %v, want 0, 1, 2, ...", seq)
+			break
+		}
+	}
+
+	// decoders without APNG support show the first frame
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			wr, wg, wb, _ := frames[0].At(x, y).RGBA()
+			if r != wr || g != wg || bl != wb {
+				t.Errorf("pixel (%d, %d) of the first frame: %v, want %v", x, y, img.At(x, y), frames[0].At(x, y))
+			}
+		}
+	}
+}
+
+func TestAPNGWriterMissingFrames(t *testing.T) {
+	var buf bytes.Buffer
+	mw, err := NewMovieWriter(&buf, "movie.apng", 3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.WriteFrame(image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err == nil {
+		t.Error("closed an animation with fewer frames than announced")
+	}
+}
@@ -0,0 +1,150 @@
+package draw
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+)
+
+// Plane returns the cross-section of f in the plane "xy", "xz" or "yz" at the given cell index
+// along the normal, as a single layer whose x and y axes are the first and second axis of the plane.
+// Vector components are reordered the same way, the third one being along the normal, so that
+// colors and arrows show the in-plane directions. aspect is the height/width ratio of a cell in the image.
+func Plane(f *data.Slice, cellsize [3]float64, plane string, index int) (s *data.Slice, aspect float64, err error) {
+	var axes [3]int // image x, image y, normal
+	switch plane {
+	case "xy":
+		axes = [3]int{X, Y, Z}
+	case "xz":
+		axes = [3]int{X, Z, Y}
+	case "yz":
+		axes = [3]int{Y, Z, X}
+	default:
+		return nil, 0, fmt.Errorf("draw: unknown plane `%s`, expected xy, xz or yz", plane)
+	}
+	size := f.Size()
+	if index < 0 || index >= size[axes[2]] {
+		return nil, 0, fmt.Errorf("draw: index %d out of range [0, %d) for plane %s", index, size[axes[2]], plane)
+	}
+	w, h := size[axes[0]], size[axes[1]]
+	s = data.NewSlice(f.NComp(), [3]int{w, h, 1})
+	in := f.Tensors()
+	out := s.Tensors()
+	for c := range out {
+		src := c
+		if f.NComp() == 3 {
+			src = axes[c]
+		}
+		for j := 0; j < h; j++ {
+			for i := 0; i < w; i++ {
+				var idx [3]int
+				idx[axes[0]], idx[axes[1]], idx[axes[2]] = i, j, index
+				out[c][0][j][i] = in[src][idx[Z]][idx[Y]][idx[X]]
+			}
+		}
+	}
+	// mirrored planes (xz seen from -y) keep a right-handed frame for the normal component
+	if f.NComp() == 3 && plane == "xz" {
+		for k, v := range s.Host()[2] {
+			s.Host()[2][k] = -v
+		}
+	}
+	return s, cellsize[axes[1]] / cellsize[axes[0]], nil
+}
+
+// Section samples f with trilinear interpolation on the nu x nv pixel centers of the parallelogram
+// origin + a*u + b*v, a and b in [0, 1]. Positions are in meters from the corner of the first cell,
+// points outside of the mesh are zero. Vector components are projected on u, v and u x v.
+func Section(f *data.Slice, cellsize [3]float64, origin, u, v data.Vector, nu, nv int) (s *data.Slice, aspect float64, err error) {
+	if nu < 1 || nv < 1 {
+		return nil, 0, fmt.Errorf("draw: section needs at least one pixel, got %d x %d", nu, nv)
+	}
+	n := u.Cross(v)
+	if n.Len() == 0 {
+		return nil, 0, fmt.Errorf("draw: the section vectors must not be parallel")
+	}
+	s = data.NewSlice(f.NComp(), [3]int{nu, nv, 1})
+	out := s.Tensors()
+	frame := [3]data.Vector{u.Div(u.Len()), v.Div(v.Len()), n.Div(n.Len())}
+	for j := 0; j < nv; j++ {
+		for i := 0; i < nu; i++ {
+			p := origin.MAdd((float64(i)+0.5)/float64(nu), u).MAdd((float64(j)+0.5)/float64(nv), v)
+			value := trilinear(f, cellsize, p)
+			if f.NComp() == 3 {
+				vec := data.Vector{value[X], value[Y], value[Z]}
+				for c := range frame {
+					out[c][0][j][i] = float32(vec.Dot(frame[c]))
+				}
+				continue
+			}
+			for c := range out {
+				out[c][0][j][i] = float32(value[c])
+			}
+		}
+	}
+	return s, (v.Len() / float64(nv)) / (u.Len() / float64(nu)), nil
+}
+
+// trilinear interpolates all the components of f between the cell centers around p.
+func trilinear(f *data.Slice, cellsize [3]float64, p data.Vector) []float64 {
+	size := f.Size()
+	value := make([]float64, f.NComp())
+	var i0 [3]int
+	var w [3]float64
+	for a := range 3 {
+		x := p[a] / cellsize[a]
+		if x < 0 || x > float64(size[a]) {
+			return value
+		}
+		x = math.Max(0, math.Min(float64(size[a]-1), x-0.5)) // in cell-center coordinates, clamped at the borders
+		i0[a] = min(int(x), size[a]-2)
+		if size[a] == 1 {
+			i0[a] = 0
+		}
+		w[a] = x - float64(i0[a])
+	}
+	in := f.Tensors()
+	for dz := 0; dz < 2; dz++ {
+		for dy := 0; dy < 2; dy++ {
+			for dx := 0; dx < 2; dx++ {
+				ix, iy, iz := min(i0[X]+dx, size[X]-1), min(i0[Y]+dy, size[Y]-1), min(i0[Z]+dz, size[Z]-1)
+				weight := lerpWeight(w[X], dx) * lerpWeight(w[Y], dy) * lerpWeight(w[Z], dz)
+				for c := range value {
+					value[c] += weight * float64(in[c][iz][iy][ix])
+				}
+			}
+		}
+	}
+	return value
+}
+
+func lerpWeight(w float64, d int) float64 {
+	if d == 0 {
+		return 1 - w
+	}
+	return w
+}
+
+// stretch resizes img with nearest neighbor interpolation so that its pixels get the given
+// height/width ratio, only ever enlarging it.
+func stretch(img *image.RGBA, aspect float64) *image.RGBA {
+	if aspect <= 0 || math.Abs(aspect-1) < 1e-3 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if aspect > 1 {
+		h = int(math.Round(float64(h) * aspect))
+	} else {
+		w = int(math.Round(float64(w) / aspect))
+	}
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.SetRGBA(x, y, img.RGBAAt(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h))
+		}
+	}
+	return out
+}
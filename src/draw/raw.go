@@ -0,0 +1,156 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+)
+
+// Scale returns the color scale used to render f: the extrema of its first component,
+// unless fmin or fmax are numbers.
+func Scale(f *data.Slice, fmin, fmax string) (min, max float32) {
+	return parseMinMax(f, fmin, fmax)
+}
+
+// encodeGray16 writes a scalar slice, averaged over z, as a 16-bit grayscale PNG where
+// 0 is min and 65535 is max.
+func encodeGray16(w io.Writer, f *data.Slice, fmin, fmax string) error {
+	if f.NComp() != 1 {
+		return fmt.Errorf("render: 16-bit png needs a scalar quantity, got %d components", f.NComp())
+	}
+	min, max := parseMinMax(f, fmin, fmax)
+	arr := f.Scalars()
+	w0, h, d := len(arr[0][0]), len(arr[0]), len(arr)
+	img := image.NewGray16(image.Rect(0, 0, w0, h))
+	for iy := 0; iy < h; iy++ {
+		for ix := 0; ix < w0; ix++ {
+			var v float32
+			for iz := 0; iz < d; iz++ {
+				v += arr[iz][iy][ix]
+			}
+			v /= float32(d)
+			x := float64((v - min) / (max - min))
+			x = math.Max(0, math.Min(1, x))
+			img.SetGray16(ix, (h-1)-iy, color.Gray16{Y: uint16(math.Round(x * 65535))})
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// TIFF tags and field types used by encodeTIFF
+const (
+	tiffShort = 3
+	tiffLong  = 4
+)
+
+type tiffEntry struct {
+	tag, typ uint16
+	values   []uint32
+}
+
+// encodeTIFF writes the raw float32 values of f as an uncompressed TIFF, one page per z layer
+// and one sample per component, with the same orientation as the other snapshots.
+func encodeTIFF(w io.Writer, f *data.Slice) error {
+	size := f.Size()
+	nx, ny, spp := size[X], size[Y], f.NComp()
+	host := f.Host()
+	le := binary.LittleEndian
+
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	_ = binary.Write(&buf, le, uint16(42))
+	next := buf.Len() // position of the offset to the next IFD
+	_ = binary.Write(&buf, le, uint32(0))
+
+	repeat := func(v uint32) []uint32 {
+		r := make([]uint32, spp)
+		for i := range r {
+			r[i] = v
+		}
+		return r
+	}
+	for iz := 0; iz < size[Z]; iz++ {
+		dataOffset := buf.Len()
+		row := make([]byte, 4*nx*spp)
+		for iy := ny - 1; iy >= 0; iy-- {
+			for ix := 0; ix < nx; ix++ {
+				i := (iz*ny+iy)*nx + ix
+				for c := 0; c < spp; c++ {
+					le.PutUint32(row[4*(ix*spp+c):], math.Float32bits(host[c][i]))
+				}
+			}
+			buf.Write(row)
+		}
+		entries := []tiffEntry{
+			{256, tiffLong, []uint32{uint32(nx)}},                // ImageWidth
+			{257, tiffLong, []uint32{uint32(ny)}},                // ImageLength
+			{258, tiffShort, repeat(32)},                         // BitsPerSample
+			{259, tiffShort, []uint32{1}},                        // Compression: none
+			{262, tiffShort, []uint32{1}},                        // PhotometricInterpretation: BlackIsZero
+			{273, tiffLong, []uint32{uint32(dataOffset)}},        // StripOffsets
+			{277, tiffShort, []uint32{uint32(spp)}},              // SamplesPerPixel
+			{278, tiffLong, []uint32{uint32(ny)}},                // RowsPerStrip
+			{279, tiffLong, []uint32{uint32(nx * ny * spp * 4)}}, // StripByteCounts
+			{284, tiffShort, []uint32{1}},                        // PlanarConfiguration: chunky
+		}
+		if spp > 1 {
+			// ExtraSamples: the components after the first one are unspecified data
+			entries = append(entries, tiffEntry{338, tiffShort, make([]uint32, spp-1)})
+		}
+		entries = append(entries, tiffEntry{339, tiffShort, repeat(3)}) // SampleFormat: IEEE float
+		// values that do not fit in the 4 bytes of an entry are written before the IFD
+		offsets := make([]uint32, len(entries))
+		for i, e := range entries {
+			if fieldSize(e) > 4 {
+				offsets[i] = uint32(buf.Len())
+				writeTIFFValues(&buf, e)
+			}
+		}
+		if buf.Len()%2 == 1 {
+			buf.WriteByte(0)
+		}
+		le.PutUint32(buf.Bytes()[next:], uint32(buf.Len()))
+		_ = binary.Write(&buf, le, uint16(len(entries)))
+		for i, e := range entries {
+			_ = binary.Write(&buf, le, e.tag)
+			_ = binary.Write(&buf, le, e.typ)
+			_ = binary.Write(&buf, le, uint32(len(e.values)))
+			if fieldSize(e) > 4 {
+				_ = binary.Write(&buf, le, offsets[i])
+				continue
+			}
+			var inline bytes.Buffer
+			writeTIFFValues(&inline, e)
+			inline.Write(make([]byte, 4-inline.Len()))
+			buf.Write(inline.Bytes())
+		}
+		next = buf.Len()
+		_ = binary.Write(&buf, le, uint32(0))
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func fieldSize(e tiffEntry) int {
+	if e.typ == tiffShort {
+		return 2 * len(e.values)
+	}
+	return 4 * len(e.values)
+}
+
+func writeTIFFValues(buf *bytes.Buffer, e tiffEntry) {
+	for _, v := range e.values {
+		if e.typ == tiffShort {
+			_ = binary.Write(buf, binary.LittleEndian, uint16(v))
+		} else {
+			_ = binary.Write(buf, binary.LittleEndian, v)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+)
+
+func TestEncodeGray16(t *testing.T) {
+	const nx, ny, nz = 3, 2, 2
+	f := data.NewSlice(1, [3]int{nx, ny, nz})
+	for i := range f.Host()[0] {
+		f.Host()[0][i] = float32(i%(nx*ny)) + float32(i/(nx*ny)) // the layers differ by 1
+	}
+
+	var buf bytes.Buffer
+	if err := encodeGray16(&buf, f, "0", "6"); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray, ok := img.(*image.Gray16)
+	if !ok {
+		t.Fatalf("decoded a %T, want a 16-bit grayscale image", img)
+	}
+	if b := gray.Bounds(); b.Dx() != nx || b.Dy() != ny {
+		t.Fatalf("image of %dx%d, want %dx%d", b.Dx(), b.Dy(), nx, ny)
+	}
+	for iy := 0; iy < ny; iy++ {
+		for ix := 0; ix < nx; ix++ {
+			mean := float64(iy*nx+ix) + 0.5 // average over z
+			want := uint16(math.Round(mean / 6 * 65535))
+			// y points up in the simulation and down in the image
+			if got := gray.Gray16At(ix, ny-1-iy).Y; got != want {
+				t.Errorf("cell (%d, %d): %d, want %d", ix, iy, got, want)
+			}
+		}
+	}
+
+	if err := encodeGray16(&buf, data.NewSlice(3, [3]int{nx, ny, nz}), "auto", "auto"); err == nil {
+		t.Error("encodeGray16 accepted a vector")
+	}
+}
+
+// tiffPage is an image file directory of a TIFF file, with the values of its entries.
+type tiffPage map[uint16][]uint32
+
+// readTIFF parses the pages of a little endian TIFF written by encodeTIFF.
+func readTIFF(t *testing.T, b []byte) []tiffPage {
+	le := binary.LittleEndian
+	if string(b[:2]) != "II" || le.Uint16(b[2:]) != 42 {
+		t.Fatalf("not a little endian TIFF: % x", b[:4])
+	}
+	var pages []tiffPage
+	for off := le.Uint32(b[4:]); off != 0; off = le.Uint32(b[off+2+12*uint32(le.Uint16(b[off:])):]) {
+		if off%2 == 1 {
+			t.Errorf("IFD at the odd offset %d", off)
+		}
+		page := tiffPage{}
+		n := int(le.Uint16(b[off:]))
+		for i := 0; i < n; i++ {
+			e := b[int(off)+2+12*i:]
+			tag, typ, count := le.Uint16(e), le.Uint16(e[2:]), int(le.Uint32(e[4:]))
+			size := 4
+			if typ == tiffShort {
+				size = 2
+			}
+			values := e[8:12]
+			if count*size > 4 {
+				values = b[le.Uint32(e[8:]):]
+			}
+			for j := 0; j < count; j++ {
+				if typ == tiffShort {
+					page[tag] = append(page[tag], uint32(le.Uint16(values[2*j:])))
+				} else {
+					page[tag] = append(page[tag], le.Uint32(values[4*j:]))
+				}
+			}
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func TestEncodeTIFF(t *testing.T) {
+	const nx, ny, nz, ncomp = 3, 2, 4, 3
+	f := data.NewSlice(ncomp, [3]int{nx, ny, nz})
+	for c := 0; c < ncomp; c++ {
+		for i := range f.Host()[c] {
+			f.Host()[c][i] = float32(c*1000+i) + 0.25
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encodeTIFF(&buf, f); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	pages := readTIFF(t, b)
+	if len(pages) != nz {
+		t.Fatalf("%d pages, want one per layer: %d", len(pages), nz)
+	}
+	for iz, page := range pages {
+		check := func(name string, tag uint16, want ...uint32) {
+			if got := page[tag]; !slices.Equal(got, want) {
+				t.Errorf("page %d: %s %v, want %v", iz, name, got, want)
+			}
+		}
+		check("width", 256, nx)
+		check("height", 257, ny)
+		check("bits per sample", 258, 32, 32, 32)
+		check("compression", 259, 1)
+		check("samples per pixel", 277, ncomp)
+		check("strip bytes", 279, nx*ny*ncomp*4)
+		check("extra samples", 338, 0, 0)
+		check("sample format", 339, 3, 3, 3)
+
+		strip := b[page[273][0]:]
+		for iy := 0; iy < ny; iy++ {
+			for ix := 0; ix < nx; ix++ {
+				for c := 0; c < ncomp; c++ {
+					// rows from the top of the image, which is the last y of the simulation
+					k := ((ny-1-iy)*nx+ix)*ncomp + c
+					got := math.Float32frombits(binary.LittleEndian.Uint32(strip[4*k:]))
+					if want := f.Host()[c][(iz*ny+iy)*nx+ix]; got != want {
+						t.Errorf("page %d, cell (%d, %d), component %d: %g, want %g", iz, ix, iy, c, got, want)
+					}
+				}
+			}
+		}
+	}
+}
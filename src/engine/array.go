@@ -11,6 +11,8 @@ import (
 func init() {
 	DeclFunc("readArrayFromFile", readArrayFromFile, "")
 	DeclFunc("readArrayFromString", readArrayFromString, "")
+	DeclFunc("Linspace", linspace, "Array of n evenly spaced values from start to stop (inclusive)")
+	DeclFunc("Concat", concat, "Array with the values of a followed by the values of b")
 }
 
 type InputArray struct {
@@ -63,3 +65,23 @@ func readArrayFromString(s string) InputArray {
 
 	return InputArray{data: result}
 }
+
+func linspace(start, stop float64, n int) InputArray {
+	if n < 1 {
+		log.Log.ErrAndExit("Linspace: n must be at least 1, got %d", n)
+	}
+	if n == 1 {
+		return InputArray{data: []float64{start}}
+	}
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = start + (stop-start)*float64(i)/float64(n-1)
+	}
+	return InputArray{data: result}
+}
+
+func concat(a, b InputArray) InputArray {
+	result := make([]float64, 0, a.Len()+b.Len())
+	result = append(result, a.data...)
+	return InputArray{data: append(result, b.data...)}
+}
@@ -20,9 +20,11 @@ func saveIfNeeded() {
 		}
 	}
 	savedQuantities.SaveIfNeeded()
+	sampleSpectraIfNeeded()
 	if Table.NeedSave() {
 		tableSave()
 	}
+	saveTableGroupsIfNeeded()
 	if EngineState.Metadata.NeedSave() {
 		EngineState.Metadata.Save()
 	}
@@ -38,19 +38,19 @@ func (s *backwardEuler) Step() {
 	Time = t0 + 0.5*DtSi // 0.5 dt makes it implicit midpoint method
 
 	// with temperature, previous torque cannot be used as predictor
-	if Temp.isZero() {
+	if zeroTemperature() {
 		cuda.Madd2(y, y0, dy1, 1, dt) // predictor euler step with previous torque
-		NormMag.normalize()
+		NormMag.renormalize()
 	}
 
 	torqueFn(dy0)
 	cuda.Madd2(y, y0, dy0, 1, dt) // y = y0 + dt * dy
-	NormMag.normalize()
+	NormMag.renormalize()
 
 	// One iteration
 	torqueFn(dy1)
 	cuda.Madd2(y, y0, dy1, 1, dt) // y = y0 + dt * dy1
-	NormMag.normalize()
+	NormMag.renormalize()
 
 	Time = t0 + DtSi
 
@@ -6,7 +6,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/MathieuMoalic/amumax/src/hooks"
 	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/notify"
 	"github.com/MathieuMoalic/amumax/src/timer"
 )
 
@@ -28,13 +30,19 @@ func CleanExit() {
 	if outputdir == "" {
 		return
 	}
+	saveSpectra()
 	drainOutput()
 	log.Log.Info("**************** Simulation Ended ****************** //")
-	Table.Flush()
+	flushTables()
 	if SyncAndLog {
 		timer.Print(os.Stdout)
 	}
 	EngineState.Metadata.Add("steps", NSteps)
 	EngineState.Metadata.End()
+	if !runFailed {
+		Hooks.Fire(hookPayload(hooks.Finish, ""))
+		notifyEnd(notify.Finished, "")
+	}
+	Hooks.Wait()
 	log.Log.FlushToFile()
 }
@@ -30,7 +30,7 @@ func (*euler) Step() {
 	setLastErr(float64(dt) * LastTorque)
 
 	cuda.Madd2(y, y, dy0, 1, dt) // y = y + dt * dy
-	NormMag.normalize()
+	NormMag.renormalize()
 	Time += DtSi
 	NSteps++
 }
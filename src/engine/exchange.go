@@ -39,6 +39,7 @@ func init() {
 	lex2.init(Aex)
 	din2.init(Dind)
 	dbulk2.init(Dbulk)
+	Dind.noMap, Dbulk.noMap = true, true
 }
 
 // Adds the current exchange field to dst
@@ -48,6 +49,10 @@ func addExchangeField(dst *data.Slice) {
 	ms := Msat.MSlice()
 	defer ms.Recycle()
 	switch {
+	case Aex.cellMap != nil && (inter || bulk):
+		log.Log.ErrAndExit("Cannot have a map on Aex together with DMI")
+	case Aex.cellMap != nil:
+		addExchangeFieldMap(dst, ms)
 	case !inter && !bulk:
 		cuda.AddExchange(dst, NormMag.Buffer(), lex2.Gpu(), ms, Regions.Gpu(), NormMag.Mesh())
 	case inter && !bulk:
@@ -20,8 +20,9 @@ var (
 
 type geom struct {
 	info
-	Buffer *data.Slice
-	shape  shape
+	Buffer  *data.Slice
+	shape   shape
+	version int // incremented whenever the geometry changes
 }
 
 func (g *geom) init() {
@@ -61,6 +62,17 @@ func (g *geom) average() []float64 {
 
 func (g *geom) Average() float64 { return g.average()[0] }
 
+// insideGeometry returns 1 in the cells of the geometry and 0 outside, to be recycled.
+func insideGeometry() *data.Slice {
+	buf := cuda.Buffer(1, GetMesh().Size())
+	if g := Geometry.Gpu(); g.IsNil() {
+		cuda.Memset(buf, 1)
+	} else {
+		cuda.Div(buf, g, g)
+	}
+	return buf
+}
+
 func (g *geom) setGeom(s shape) {
 	setBusy(true)
 	defer setBusy(false)
@@ -71,6 +83,7 @@ func (g *geom) setGeom(s shape) {
 	}
 
 	g.shape = s
+	g.version++
 	if g.Gpu().IsNil() {
 		g.Buffer = cuda.NewSlice(1, g.Mesh().Size())
 	}
@@ -101,6 +114,7 @@ func (g *geom) setGeom(s shape) {
 				}
 
 				if edgeSmooth != 0 { // center is sufficient if we're not really smoothing
+					vertexCell = &[3]int{ix, iy, iz} // the shapes defined per cell answer for this cell
 					for _, Δx := range []float64{-cx / 2, cx / 2} {
 						for _, Δy := range []float64{-cy / 2, cy / 2} {
 							for _, Δz := range []float64{-cz / 2, cz / 2} {
@@ -112,6 +126,7 @@ func (g *geom) setGeom(s shape) {
 							}
 						}
 					}
+					vertexCell = nil
 				}
 
 				switch {
@@ -154,7 +169,7 @@ func (g *geom) setGeom(s shape) {
 		data.Copy(NormMag.Buffer(), mhost)
 	}
 
-	NormMag.normalize() // removes m outside vol
+	NormMag.renormalize() // removes m outside vol
 }
 
 // Sample edgeSmooth^3 points inside the cell to estimate its volume.
@@ -203,6 +218,7 @@ func (g *geom) shift(dx int) {
 	newv := float32(1) // initially fill edges with 1's
 	cuda.ShiftX(s2, s, dx, newv, newv)
 	data.Copy(s, s2)
+	g.version++
 
 	n := GetMesh().Size()
 	x1, x2 := shiftDirtyRange(dx)
@@ -232,6 +248,7 @@ func (g *geom) shiftY(dy int) {
 	newv := float32(1) // initially fill edges with 1's
 	cuda.ShiftY(s2, s, dy, newv, newv)
 	data.Copy(s, s2)
+	g.version++
 
 	n := GetMesh().Size()
 	y1, y2 := shiftDirtyRange(dy)
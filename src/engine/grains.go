@@ -0,0 +1,319 @@
+package engine
+
+// Voronoi grains with a log-normal size distribution, for granular media. The grains are the cells of a
+// Laguerre (power) diagram in the xy plane, extruded along z: every grain has a radius drawn from the
+// distribution, and a point belongs to the grain with the smallest |r - center|² - radius².
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/zarr"
+)
+
+var (
+	grainLloydIterations = 0
+	grainBoundaryWidth   = 0.0
+	grainBoundaryRegion  = -1
+)
+
+func init() {
+	DeclFunc("LogNormalGrains", logNormalGrains, "LogNormalGrains(name, diameter, sigma, startRegion, numRegions, seed) "+
+		"divides the xy plane into grains with a log-normal distribution of the equivalent circle diameter (median "+
+		"diameter in m, sigma of its logarithm), with random regions in startRegion..startRegion+numRegions-1. "+
+		"The grain centers and areas are written to the zarr group name")
+	declVar("GrainLloydIterations", &grainLloydIterations, "Number of Lloyd iterations of LogNormalGrains, "+
+		"moving every grain center to the centroid of its grain for more regular grains")
+	declVar("GrainBoundaryWidth", &grainBoundaryWidth, "Width (m) of the boundaries between the grains of "+
+		"LogNormalGrains, 0 for no boundaries")
+	declVar("GrainBoundaryRegion", &grainBoundaryRegion, "Region of the grain boundaries of LogNormalGrains")
+}
+
+type grain struct {
+	x, y, r float64 // center and radius (m), with x, y in [0, size)
+	region  int
+}
+
+// grainMap finds the grain of a point, by searching grains binned on a square grid in rings of bins
+// around the point.
+type grainMap struct {
+	grains     []grain
+	size       [2]float64 // size of the plane (m)
+	pbc        [2]bool
+	bin        float64
+	nbin       [2]int
+	bins       [][]int
+	rmax       float64
+	candidates []int
+}
+
+func logNormalGrains(name string, diameter, sigma float64, startRegion, numRegions, seed int) {
+	setBusy(true)
+	defer setBusy(false)
+
+	if diameter <= 0 || sigma < 0 {
+		log.Log.ErrAndExit("LogNormalGrains: the diameter should be positive and sigma not negative")
+	}
+	if startRegion < 0 || numRegions < 1 || startRegion+numRegions > NREGION {
+		log.Log.ErrAndExit("LogNormalGrains: regions %d to %d are not all in 0-%d", startRegion, startRegion+numRegions-1, NREGION-1)
+	}
+	if grainBoundaryWidth > 0 && (grainBoundaryRegion < 0 || grainBoundaryRegion >= NREGION) {
+		log.Log.ErrAndExit("LogNormalGrains: set GrainBoundaryRegion (0-%d) to use grain boundaries", NREGION-1)
+	}
+
+	m := GetMesh()
+	n, c := m.Size(), m.CellSize()
+	g := newGrainMap([2]float64{float64(n[X]) * c[X], float64(n[Y]) * c[Y]}, [2]bool{m.PBCx != 0, m.PBCy != 0},
+		diameter, sigma, startRegion, numRegions, int64(seed))
+	log.Log.Info("LogNormalGrains: %d grains", len(g.grains))
+
+	// label the cells, then move the centers to the centroids of their grains
+	labels := make([]int, n[X]*n[Y])
+	var area []float64
+	var centroid [][2]float64
+	for iter := 0; ; iter++ {
+		area = make([]float64, len(g.grains))
+		centroid = make([][2]float64, len(g.grains))
+		for iy := 0; iy < n[Y]; iy++ {
+			for ix := 0; ix < n[X]; ix++ {
+				x, y := (float64(ix)+0.5)*c[X], (float64(iy)+0.5)*c[Y]
+				i := g.nearest(x, y)
+				labels[iy*n[X]+ix] = i
+				dx, dy := g.displacement(g.grains[i], x, y)
+				area[i] += c[X] * c[Y]
+				centroid[i][0] += dx * c[X] * c[Y]
+				centroid[i][1] += dy * c[X] * c[Y]
+			}
+		}
+		for i := range centroid {
+			if area[i] > 0 {
+				centroid[i] = g.wrap(g.grains[i].x+centroid[i][0]/area[i], g.grains[i].y+centroid[i][1]/area[i])
+			} else {
+				centroid[i] = [2]float64{g.grains[i].x, g.grains[i].y}
+			}
+		}
+		if iter == grainLloydIterations {
+			break
+		}
+		for i := range g.grains {
+			g.grains[i].x, g.grains[i].y = centroid[i][0], centroid[i][1]
+		}
+		g.rebin()
+	}
+
+	regions := make([]int, len(labels))
+	for iy := 0; iy < n[Y]; iy++ {
+		for ix := 0; ix < n[X]; ix++ {
+			i := labels[iy*n[X]+ix]
+			regions[iy*n[X]+ix] = g.grains[i].region
+			x, y := (float64(ix)+0.5)*c[X], (float64(iy)+0.5)*c[Y]
+			if grainBoundaryWidth > 0 && g.boundaryDistance(i, x, y) < grainBoundaryWidth/2 {
+				regions[iy*n[X]+ix] = grainBoundaryRegion
+			}
+		}
+	}
+	f := func(x, y, z float64) int {
+		i, _, ok := meshCell(x, y, z)
+		if !ok {
+			return -1
+		}
+		return regions[i%(n[X]*n[Y])]
+	}
+	Regions.hist = append(Regions.hist, f)
+	Regions.render(f)
+	for r := startRegion; r < startRegion+numRegions; r++ {
+		Regions.AddIndex(r)
+	}
+	if grainBoundaryWidth > 0 {
+		Regions.AddIndex(grainBoundaryRegion)
+	}
+	g.save(name, centroid, area)
+}
+
+func newGrainMap(size [2]float64, pbc [2]bool, diameter, sigma float64, startRegion, numRegions int, seed int64) *grainMap {
+	rng := rand.New(rand.NewSource(seed))
+	// the mean grain area is π/4 <d²>, with <d²> = median² exp(2σ²) for a log-normal d
+	meanArea := math.Pi / 4 * diameter * diameter * math.Exp(2*sigma*sigma)
+	N := max(1, int(math.Round(size[0]*size[1]/meanArea)))
+	g := &grainMap{grains: make([]grain, N), size: size, pbc: pbc}
+	for i := range g.grains {
+		g.grains[i] = grain{
+			x:      rng.Float64() * size[0],
+			y:      rng.Float64() * size[1],
+			r:      diameter / 2 * math.Exp(sigma*rng.NormFloat64()),
+			region: startRegion + rng.Intn(numRegions),
+		}
+		g.rmax = math.Max(g.rmax, g.grains[i].r)
+	}
+	g.bin = math.Sqrt(size[0] * size[1] / float64(N))
+	g.rebin()
+	return g
+}
+
+func (g *grainMap) rebin() {
+	g.nbin = [2]int{max(1, int(g.size[0]/g.bin)), max(1, int(g.size[1]/g.bin))}
+	g.bins = make([][]int, g.nbin[0]*g.nbin[1])
+	for i, gr := range g.grains {
+		bx, by := g.binOf(gr.x, gr.y)
+		g.bins[by*g.nbin[0]+bx] = append(g.bins[by*g.nbin[0]+bx], i)
+	}
+}
+
+func (g *grainMap) binOf(x, y float64) (int, int) {
+	bx := min(max(int(x/g.size[0]*float64(g.nbin[0])), 0), g.nbin[0]-1)
+	by := min(max(int(y/g.size[1]*float64(g.nbin[1])), 0), g.nbin[1]-1)
+	return bx, by
+}
+
+// ring returns the grains in the bins at Chebyshev distance k from the bin of (x, y).
+func (g *grainMap) ring(x, y float64, k int) []int {
+	bx, by := g.binOf(x, y)
+	g.candidates = g.candidates[:0]
+	for jy := by - k; jy <= by+k; jy++ {
+		for jx := bx - k; jx <= bx+k; jx++ {
+			if jx != bx-k && jx != bx+k && jy != by-k && jy != by+k {
+				continue
+			}
+			ix, iy, ok := g.wrapBin(jx, jy, bx, by)
+			if ok {
+				g.candidates = append(g.candidates, g.bins[iy*g.nbin[0]+ix]...)
+			}
+		}
+	}
+	return g.candidates
+}
+
+// wrapBin returns the bin at (jx, jy), with periodic boundaries the bin at the same offset from (bx, by) as
+// its nearest periodic image, or false if there is none.
+func (g *grainMap) wrapBin(jx, jy, bx, by int) (int, int, bool) {
+	j, b := [2]int{jx, jy}, [2]int{bx, by}
+	for k := range j {
+		if !g.pbc[k] {
+			if j[k] < 0 || j[k] >= g.nbin[k] {
+				return 0, 0, false
+			}
+			continue
+		}
+		// every bin has a single offset in -nbin/2 .. nbin-1-nbin/2, so that wide rings do not visit bins twice
+		if o := j[k] - b[k]; o < -g.nbin[k]/2 || o > g.nbin[k]-1-g.nbin[k]/2 {
+			return 0, 0, false
+		}
+		j[k] = (j[k] + g.nbin[k]) % g.nbin[k]
+	}
+	return j[0], j[1], true
+}
+
+// maxRing is the largest ring that can contain grains.
+func (g *grainMap) maxRing() int {
+	return max(g.nbin[0], g.nbin[1])
+}
+
+// ringDistance is a lower bound of the distance between (x, y) and the grains in ring k.
+func (g *grainMap) ringDistance(k int) float64 {
+	return math.Max(0, float64(k-1)) * math.Min(g.size[0]/float64(g.nbin[0]), g.size[1]/float64(g.nbin[1]))
+}
+
+// displacement returns (x, y) - center of gr, using the nearest periodic image.
+func (g *grainMap) displacement(gr grain, x, y float64) (float64, float64) {
+	d := [2]float64{x - gr.x, y - gr.y}
+	for k := range d {
+		if g.pbc[k] {
+			d[k] -= g.size[k] * math.Round(d[k]/g.size[k])
+		}
+	}
+	return d[0], d[1]
+}
+
+// images returns the offsets of the periodic images along axis k, from (x, y) - center.
+func (g *grainMap) images(k int) []float64 {
+	if !g.pbc[k] {
+		return []float64{0}
+	}
+	return []float64{-g.size[k], 0, g.size[k]}
+}
+
+func (g *grainMap) wrap(x, y float64) [2]float64 {
+	r := [2]float64{x, y}
+	for k := range r {
+		if g.pbc[k] {
+			r[k] -= g.size[k] * math.Floor(r[k]/g.size[k])
+		} else {
+			r[k] = math.Min(math.Max(r[k], 0), g.size[k])
+		}
+	}
+	return r
+}
+
+func (g *grainMap) power(i int, x, y float64) float64 {
+	dx, dy := g.displacement(g.grains[i], x, y)
+	return dx*dx + dy*dy - g.grains[i].r*g.grains[i].r
+}
+
+// nearest returns the grain of (x, y).
+func (g *grainMap) nearest(x, y float64) int {
+	best, bestPower := -1, math.Inf(1)
+	for k := 0; k <= g.maxRing(); k++ {
+		if d := g.ringDistance(k); best >= 0 && d*d-g.rmax*g.rmax > bestPower {
+			break
+		}
+		for _, i := range g.ring(x, y, k) {
+			if p := g.power(i, x, y); p < bestPower {
+				best, bestPower = i, p
+			}
+		}
+	}
+	return best
+}
+
+// boundaryDistance returns the distance between (x, y), in grain i, and the nearest boundary of grain i.
+func (g *grainMap) boundaryDistance(i int, x, y float64) float64 {
+	own := g.power(i, x, y)
+	dx, dy := g.displacement(g.grains[i], x, y)
+	toCenter := math.Sqrt(dx*dx + dy*dy)
+	best := math.Inf(1)
+	for k := 0; k <= g.maxRing(); k++ {
+		// the boundary with j is at (power_j - power_i) / (2 |c_i - c_j|), and |c_i - c_j| <= toCenter + d
+		if d := g.ringDistance(k); d*d-g.rmax*g.rmax-own > 2*best*(toCenter+d) {
+			break
+		}
+		for _, j := range g.ring(x, y, k) {
+			// the image of j nearest to (x, y) and its neighbouring images, whose boundary can be closer when the
+			// radii differ a lot, which includes the other images of i
+			jx, jy := g.displacement(g.grains[j], x, y)
+			r2 := g.grains[j].r * g.grains[j].r
+			for _, ox := range g.images(X) {
+				for _, oy := range g.images(Y) {
+					px, py := jx+ox, jy+oy
+					dist := math.Hypot(px-dx, py-dy)
+					if dist == 0 {
+						continue // i itself
+					}
+					best = math.Min(best, (px*px+py*py-r2-own)/(2*dist))
+				}
+			}
+		}
+	}
+	return best
+}
+
+// save writes the centroid (m, in the coordinates of the shapes), area (m²), equivalent circle diameter (m)
+// and region of every grain to the zarr group name.
+func (g *grainMap) save(name string, centroid [][2]float64, area []float64) {
+	columns := map[string][]float64{}
+	for i, gr := range g.grains {
+		columns["x"] = append(columns["x"], centroid[i][0]-g.size[0]/2-totalShift)
+		columns["y"] = append(columns["y"], centroid[i][1]-g.size[1]/2-totalYShift)
+		columns["area"] = append(columns["area"], area[i])
+		columns["diameter"] = append(columns["diameter"], 2*math.Sqrt(area[i]/math.Pi))
+		columns["region"] = append(columns["region"], float64(gr.region))
+	}
+	zarr.InitZgroup(name, OD())
+	zarr.SaveZattrs(OD()+name, map[string]any{
+		"grains": len(g.grains), "lloyd_iterations": grainLloydIterations, "boundary_width": grainBoundaryWidth,
+		"boundary_region": grainBoundaryRegion, "units": map[string]string{"x": "m", "y": "m", "area": "m2", "diameter": "m"},
+	})
+	for col, values := range columns {
+		zarr.SaveFloat64Array(OD()+name+"/"+col, values)
+	}
+}
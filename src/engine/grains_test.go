@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// The search by rings of bins finds the same grain and boundary as checking every grain.
+func TestGrainMapNearest(t *testing.T) {
+	tests := []struct {
+		name  string
+		pbc   [2]bool
+		sigma float64
+	}{
+		{"open", [2]bool{false, false}, 0.3},
+		{"periodic", [2]bool{true, true}, 0.3},
+		{"periodic along x", [2]bool{true, false}, 0.3},
+		{"broad sizes", [2]bool{true, true}, 1},
+	}
+	for _, tt := range tests {
+		size := [2]float64{500e-9, 300e-9}
+		g := newGrainMap(size, tt.pbc, 40e-9, tt.sigma, 1, 10, 7)
+		rng := rand.New(rand.NewSource(1))
+		for n := 0; n < 500; n++ {
+			x, y := rng.Float64()*size[0], rng.Float64()*size[1]
+
+			want, wantPower := -1, math.Inf(1)
+			for i := range g.grains {
+				if p := g.power(i, x, y); p < wantPower {
+					want, wantPower = i, p
+				}
+			}
+			i := g.nearest(x, y)
+			if i != want {
+				t.Errorf("%s: grain %d at (%g, %g), want %d", tt.name, i, x, y, want)
+				continue
+			}
+
+			// every periodic image of the other grains
+			dx, dy := g.displacement(g.grains[i], x, y)
+			wantDist := math.Inf(1)
+			for _, gr := range g.grains {
+				for _, ox := range periodicImages(tt.pbc[0], size[0]) {
+					for _, oy := range periodicImages(tt.pbc[1], size[1]) {
+						jx, jy := x-gr.x-ox, y-gr.y-oy
+						if dist := math.Hypot(jx-dx, jy-dy); dist > 0 {
+							wantDist = math.Min(wantDist, (jx*jx+jy*jy-gr.r*gr.r-wantPower)/(2*dist))
+						}
+					}
+				}
+			}
+			if got := g.boundaryDistance(i, x, y); math.Abs(got-wantDist) > 1e-9*size[0] {
+				t.Errorf("%s: (%g, %g) is %g from the boundary of grain %d, want %g", tt.name, x, y, got, i, wantDist)
+			}
+		}
+	}
+}
+
+// periodicImages returns the offsets of the periodic images along an axis, up to two periods away.
+func periodicImages(pbc bool, size float64) []float64 {
+	if !pbc {
+		return []float64{0}
+	}
+	return []float64{-2 * size, -size, 0, size, 2 * size}
+}
+
+func TestGrainMapBoundary(t *testing.T) {
+	// two grains of equal size meet halfway, and across the periodic boundary
+	g := &grainMap{
+		grains: []grain{{x: 20e-9, y: 50e-9, r: 10e-9}, {x: 60e-9, y: 50e-9, r: 10e-9}},
+		size:   [2]float64{100e-9, 100e-9},
+		pbc:    [2]bool{true, false},
+		bin:    50e-9,
+		rmax:   10e-9,
+	}
+	g.rebin()
+	tests := []struct {
+		x, dist float64
+		grain   int
+	}{
+		{30e-9, 10e-9, 0},
+		{45e-9, 5e-9, 1},
+		{5e-9, 15e-9, 0}, // the boundary at x = 40 nm is further than the one at x = -10 nm
+		{95e-9, 5e-9, 0}, // closer to the image of grain 0 at x = 120 nm
+	}
+	for _, tt := range tests {
+		if i := g.nearest(tt.x, 50e-9); i != tt.grain {
+			t.Errorf("x = %g: grain %d, want %d", tt.x, i, tt.grain)
+		}
+		if d := g.boundaryDistance(tt.grain, tt.x, 50e-9); math.Abs(d-tt.dist) > 1e-18 {
+			t.Errorf("x = %g: %g from the boundary, want %g", tt.x, d, tt.dist)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package engine
+
+// Heat diffusion: the temperature evolves by the heat equation instead of being set by Temp, optionally in the
+// two-temperature model with separate electron and lattice temperatures. The electron temperature (the lattice
+// temperature without the two-temperature model) sets the thermal field B_therm.
+//
+//	Cel dTe/dt = ∇·(Kel ∇Te) - Gep (Te - Tl) + Q
+//	Cth dTl/dt = ∇·(Kth ∇Tl) + Gep (Te - Tl) - Gsub (Tl - Temp)
+//
+// Without the two-temperature model, Te = Tl and the source Q heats the lattice directly. The heat equation
+// is integrated with explicit Euler substeps after every time step of the solver. The conduction reuses the
+// exchange kernel, which computes 2K/C ∇²T for a field with the conductivity K as stiffness and the heat
+// capacity C as Msat, with free (zero flux) boundaries at the edges of the geometry.
+
+import (
+	"math"
+
+	"github.com/MathieuMoalic/amumax/src/cuda"
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/log"
+)
+
+var (
+	Kth         = newScalarParam("Kth", "W/(m K)", "Thermal conductivity of the lattice", &kth2)
+	Cth         = newScalarParam("Cth", "J/(m3 K)", "Heat capacity of the lattice")
+	Kel         = newScalarParam("Kel", "W/(m K)", "Thermal conductivity of the electrons (two-temperature model)", &kel2)
+	Cel         = newScalarParam("Cel", "J/(m3 K)", "Heat capacity of the electrons (two-temperature model)")
+	Gep         = newScalarParam("Gep", "W/(m3 K)", "Electron-phonon coupling (two-temperature model)")
+	Gsub        = newScalarParam("Gsub", "W/(m3 K)", "Coupling of the lattice to a substrate at the temperature Temp")
+	Resistivity = newScalarParam("Resistivity", "Ohm m", "Electrical resistivity, for the Joule heating by J")
+	QHeat       = newScalarExcitation("Q_heat", "W/m3", "Heat source density, e.g. of a laser pulse")
+	kth2        exchParam // inter-cell Kth
+	kel2        exchParam // inter-cell Kel
+
+	TEl  = newScalarField("T_e", "K", "Electron temperature, which sets the thermal field", heatElectronTemp)
+	TLat = newScalarField("T_l", "K", "Lattice temperature", heatLatticeTemp)
+
+	heatDiffusion  = false
+	twoTemperature = false
+	heat           heatSolver
+)
+
+// fraction of the stability limit of the explicit substeps
+const heatCourant = 0.5
+
+func init() {
+	kth2.init(Kth)
+	kel2.init(Kel)
+	Kth.noMap, Kel.noMap = true, true
+	declVar("HeatDiffusion", &heatDiffusion, "Evolves the temperature by the heat equation, starting from Temp (default=false)")
+	declVar("TwoTemperature", &twoTemperature, "Uses the two-temperature model for the heat diffusion (default=false)")
+	DeclFunc("LaserSpot", laserSpot, "LaserSpot(x0, y0, radius) is a mask with the Gaussian profile exp(-r²/(2 radius²)) "+
+		"around (x0, y0), e.g. Q_heat.Add(LaserSpot(0, 0, 100e-9), 1e20*exp(-pow((t-50e-12)/20e-12, 2)))")
+	PostStep(heat.step)
+}
+
+// heatSolver holds the temperatures on the GPU, as they were at time.
+type heatSolver struct {
+	te, tl  *data.Slice // electron and lattice temperature, te is nil without the two-temperature model
+	time    float64
+	version int // incremented whenever the temperatures change
+}
+
+// active returns whether the temperature comes from the heat equation.
+func (h *heatSolver) active() bool { return heatDiffusion && !relaxing }
+
+// electron returns the temperature that sets the thermal field.
+func (h *heatSolver) electron() *data.Slice {
+	if h.te != nil {
+		return h.te
+	}
+	return h.tl
+}
+
+// zeroTemperature returns whether there is no thermal field, with neither Temp nor heat diffusion.
+func zeroTemperature() bool {
+	return Temp.isZero() && !heat.active()
+}
+
+// thermalTemperature returns the temperature per cell for the thermal field, to be recycled.
+func thermalTemperature() cuda.MSlice {
+	if !heat.active() {
+		return Temp.MSlice()
+	}
+	buf := cuda.Buffer(1, GetMesh().Size())
+	evalTemperature(buf)
+	return cuda.ToMSlice(buf)
+}
+
+// evalTemperature sets dst to the temperature per cell that sets the thermal field, from the heat equation
+// or from Temp.
+func evalTemperature(dst *data.Slice) {
+	if !heat.active() {
+		Temp.EvalTo(dst)
+		return
+	}
+	heat.init()
+	data.Copy(dst, heat.electron())
+}
+
+// init starts from Temp if the temperatures were not allocated yet, the mesh or model changed, or the time went
+// back, e.g. for the next run of a Sweep.
+func (h *heatSolver) init() {
+	for _, c := range Temp.children {
+		if p, ok := c.(*regionwiseScalar); ok && p.tempDep != nil {
+			log.Log.ErrAndExit("%s has a temperature law of Temp, which cannot be used with HeatDiffusion", p.name)
+		}
+	}
+	size := GetMesh().Size()
+	if h.tl != nil && h.tl.Size() == size && (h.te != nil) == twoTemperature && Time >= h.time {
+		return
+	}
+	h.free()
+	inside := insideGeometry()
+	defer cuda.Recycle(inside)
+	if zeroInside(Cth, inside) || (twoTemperature && zeroInside(Cel, inside)) {
+		log.Log.ErrAndExit("Heat diffusion needs the heat capacity Cth in every region of the geometry, and Cel with the two-temperature model")
+	}
+	h.tl = cuda.NewSlice(1, size)
+	Temp.EvalTo(h.tl)
+	cuda.Mul(h.tl, h.tl, inside)
+	if twoTemperature {
+		h.te = cuda.NewSlice(1, size)
+		data.Copy(h.te, h.tl)
+	}
+	h.time = Time
+	h.version++
+	log.Log.Info("Heat diffusion starts from Temp at t = %g s", Time)
+}
+
+func (h *heatSolver) free() {
+	if h.tl != nil {
+		h.tl.Free()
+		h.tl = nil
+	}
+	if h.te != nil {
+		h.te.Free()
+		h.te = nil
+	}
+}
+
+// zeroInside returns whether p is zero in a cell of the geometry, given by insideGeometry.
+func zeroInside(p *regionwiseScalar, inside *data.Slice) bool {
+	buf, _ := p.Slice()
+	defer cuda.Recycle(buf)
+	// Div gives 0 where p is zero and 1 elsewhere, which leaves 1 inside where p is zero
+	cuda.Div(buf, buf, buf)
+	cuda.Madd2(buf, inside, buf, 1, -1)
+	cuda.Mul(buf, buf, inside)
+	return cuda.MaxAbs(buf) != 0
+}
+
+// step brings the temperatures from h.time to Time.
+func (h *heatSolver) step() {
+	if !h.active() {
+		if !relaxing { // relaxing sets the time back when it is done
+			h.time = Time
+		}
+		return
+	}
+	h.init()
+	if Time == h.time {
+		return
+	}
+	size := GetMesh().Size()
+	inside := insideGeometry()
+	defer cuda.Recycle(inside)
+	cth, _ := Cth.Slice()
+	defer cuda.Recycle(cth)
+	q := h.source()
+	defer cuda.Recycle(q)
+	// substrate sink -Gsub (Tl - Temp) = sub - Gsub Tl
+	gsub, _ := Gsub.Slice()
+	defer cuda.Recycle(gsub)
+	sub := cuda.Buffer(1, size)
+	defer cuda.Recycle(sub)
+	Temp.EvalTo(sub)
+	cuda.Mul(sub, sub, gsub)
+
+	var cel, gep *data.Slice
+	if twoTemperature {
+		cel, _ = Cel.Slice()
+		defer cuda.Recycle(cel)
+		gep, _ = Gep.Slice()
+		defer cuda.Recycle(gep)
+	}
+
+	rateL := cuda.Buffer(1, size)
+	defer cuda.Recycle(rateL)
+	rateE := cuda.Buffer(1, size)
+	defer cuda.Recycle(rateE)
+	power := cuda.Buffer(1, size)
+	defer cuda.Recycle(power)
+
+	n, dt := heatSubsteps(Time-h.time, h.maxDt(cth, cel, gsub, gep))
+	for i := 0; i < n; i++ {
+		// lattice: sub - Gsub Tl, plus Gep (Te - Tl) or Q
+		cuda.Mul(power, gsub, h.tl)
+		cuda.Madd2(power, sub, power, 1, -1)
+		if twoTemperature {
+			// exchange: Gep (Te - Tl), into rateE as a temporary
+			cuda.Madd2(rateE, h.te, h.tl, 1, -1)
+			cuda.Mul(rateE, rateE, gep)
+			cuda.Add(power, power, rateE)
+		} else {
+			cuda.Add(power, power, q)
+		}
+		heatRate(rateL, h.tl, power, cth, &kth2, inside)
+
+		if twoTemperature {
+			// electrons: Q - Gep (Te - Tl)
+			cuda.Madd2(power, h.te, h.tl, 1, -1)
+			cuda.Mul(power, power, gep)
+			cuda.Madd2(power, q, power, 1, -1)
+			heatRate(rateE, h.te, power, cel, &kel2, inside)
+			cuda.Madd2(h.te, h.te, rateE, 1, dt)
+			cuda.Mul(h.te, h.te, inside)
+		}
+		cuda.Madd2(h.tl, h.tl, rateL, 1, dt)
+		cuda.Mul(h.tl, h.tl, inside)
+	}
+	h.time = Time
+	h.version++
+}
+
+// heatRate sets dst to dT/dt = (power + ∇·(K ∇T)) / C.
+func heatRate(dst, T, power, c *data.Slice, k *exchParam, inside *data.Slice) {
+	size := T.Size()
+	cuda.Div(dst, power, c)
+
+	// (T, 1, 0) inside the geometry and 0 outside, so that the kernel treats the outside as a free boundary
+	// even where T = 0
+	buf := cuda.Buffer(3, size)
+	defer cuda.Recycle(buf)
+	data.Copy(buf.Comp(X), T)
+	data.Copy(buf.Comp(Y), inside)
+	cuda.Zero(buf.Comp(Z))
+	lap := cuda.Buffer(3, size)
+	defer cuda.Recycle(lap)
+	cuda.Zero(lap)
+	cuda.AddExchange(lap, buf, k.Gpu(), cuda.ToMSlice(c), Regions.Gpu(), GetMesh())
+	cuda.Madd2(dst, dst, lap.Comp(X), 1, 0.5)
+}
+
+// source returns the heat source density Q_heat plus the Joule heating Resistivity |J|², to be recycled.
+func (h *heatSolver) source() *data.Slice {
+	q, _ := QHeat.Slice()
+	if Resistivity.isZero() || J.isZero() {
+		return q
+	}
+	j, r := J.Slice()
+	if r {
+		defer cuda.Recycle(j)
+	}
+	j2 := cuda.Buffer(1, q.Size())
+	defer cuda.Recycle(j2)
+	cuda.Zero(j2)
+	cuda.AddDotProduct(j2, 1, j, j)
+	rho, _ := Resistivity.Slice()
+	defer cuda.Recycle(rho)
+	cuda.Mul(j2, j2, rho)
+	cuda.Add(q, q, j2)
+	return q
+}
+
+// heatSubsteps splits the interval into the fewest equal substeps no longer than maxDt. There is at least one
+// substep, so that the sources are applied even without conduction or coupling, when maxDt is infinite.
+func heatSubsteps(interval, maxDt float64) (int, float32) {
+	n := max(1, int(math.Ceil(interval/maxDt)))
+	return n, float32(interval / float64(n))
+}
+
+// maxDt returns the largest stable substep, from the largest rate (K Σ 2/dx² + G) / C of each temperature.
+func (h *heatSolver) maxDt(cth, cel, gsub, gep *data.Slice) float64 {
+	c := GetMesh().CellSize()
+	w := 2/(c[X]*c[X]) + 2/(c[Y]*c[Y])
+	if GetMesh().Size()[Z] > 1 {
+		w += 2 / (c[Z] * c[Z])
+	}
+	size := GetMesh().Size()
+	buf := cuda.Buffer(1, size)
+	defer cuda.Recycle(buf)
+	rate := func(k *regionwiseScalar, g []*data.Slice, capacity *data.Slice) float64 {
+		k.EvalTo(buf)
+		cuda.Madd2(buf, buf, buf, float32(w), 0)
+		for _, gi := range g {
+			cuda.Add(buf, buf, gi)
+		}
+		cuda.Div(buf, buf, capacity)
+		return float64(cuda.MaxAbs(buf))
+	}
+	maxRate := 0.0
+	if twoTemperature {
+		maxRate = math.Max(rate(Kth, []*data.Slice{gsub, gep}, cth), rate(Kel, []*data.Slice{gep}, cel))
+	} else {
+		maxRate = rate(Kth, []*data.Slice{gsub}, cth)
+	}
+	if maxRate == 0 {
+		return math.Inf(1)
+	}
+	return heatCourant / maxRate
+}
+
+func heatLatticeTemp(dst *data.Slice) {
+	if !heatDiffusion || heat.tl == nil {
+		Temp.EvalTo(dst)
+		return
+	}
+	data.Copy(dst, heat.tl)
+}
+
+func heatElectronTemp(dst *data.Slice) {
+	if !heatDiffusion || heat.tl == nil {
+		Temp.EvalTo(dst)
+		return
+	}
+	data.Copy(dst, heat.electron())
+}
+
+// laserSpot returns a Gaussian mask around (x0, y0) in the xy plane, uniform along z.
+func laserSpot(x0, y0, radius float64) *data.Slice {
+	if radius <= 0 {
+		log.Log.ErrAndExit("LaserSpot: the radius should be positive, have %v", radius)
+	}
+	n := GetMesh().Size()
+	mask := data.NewSlice(1, n)
+	values := mask.Scalars()
+	for iz := 0; iz < n[Z]; iz++ {
+		for iy := 0; iy < n[Y]; iy++ {
+			for ix := 0; ix < n[X]; ix++ {
+				r := index2Coord(ix, iy, iz)
+				d2 := (r[X]-x0)*(r[X]-x0) + (r[Y]-y0)*(r[Y]-y0)
+				values[iz][iy][ix] = float32(math.Exp(-d2 / (2 * radius * radius)))
+			}
+		}
+	}
+	return mask
+}
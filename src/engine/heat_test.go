@@ -0,0 +1,27 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHeatSubsteps(t *testing.T) {
+	tests := []struct {
+		interval, maxDt float64
+		n               int
+	}{
+		{1e-12, math.Inf(1), 1}, // no conduction nor coupling
+		{1e-12, 1e-12, 1},
+		{1e-12, 0.3e-12, 4},
+		{1e-12, 2e-12, 1},
+	}
+	for _, tt := range tests {
+		n, dt := heatSubsteps(tt.interval, tt.maxDt)
+		if n != tt.n {
+			t.Errorf("heatSubsteps(%g, %g): %d substeps, want %d", tt.interval, tt.maxDt, n, tt.n)
+		}
+		if float64(dt) > tt.maxDt {
+			t.Errorf("heatSubsteps(%g, %g): substep %g is longer than the limit", tt.interval, tt.maxDt, dt)
+		}
+	}
+}
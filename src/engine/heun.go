@@ -39,7 +39,7 @@ func (*heun) Step() {
 	if err < MaxErr || DtSi <= MinDt || FixDt != 0 { // mindt check to avoid infinite loop
 		// step OK
 		cuda.Madd3(y, y, dy, dy0, 1, 0.5*dt, -0.5*dt)
-		NormMag.normalize()
+		NormMag.renormalize()
 		NSteps++
 		adaptDt(math.Pow(MaxErr/err, 1./2.))
 		setLastErr(err)
@@ -0,0 +1,63 @@
+package engine
+
+// Run-level hooks: commands or webhooks invoked on start, save, table flush, finish and error.
+
+import (
+	"github.com/MathieuMoalic/amumax/src/hooks"
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/notify"
+)
+
+var (
+	Hooks     hooks.Registry
+	runFailed bool // set once on-error fired, on-finish and the finished notification are then skipped
+)
+
+func init() {
+	DeclFunc("AddHook", addHook, "Run a command (needs --insecure) or POST a JSON payload to an http(s) URL "+
+		"on an event: on-start, on-save, on-save(quantity), on-table-flush, on-finish or on-error")
+	log.Log.OnErrAndExit(FireError)
+}
+
+func addHook(event, target string) {
+	h, err := hooks.NewHook(event, target)
+	if err != nil {
+		log.Log.ErrAndExit("AddHook: %v", err)
+	}
+	Hooks.Add(h)
+}
+
+// AddHookSpec registers a hook given on the command line as "event=target".
+func AddHookSpec(spec string) {
+	h, err := hooks.Parse(spec)
+	if err != nil {
+		log.Log.ErrAndExit("Error: %v", err)
+	}
+	Hooks.Add(h)
+}
+
+// hookPayload describes the current state of the run. It must be created on the simulation goroutine,
+// before handing it over to the asynchronous output queue.
+func hookPayload(event hooks.Event, quantity string, files ...string) hooks.Payload {
+	return hooks.Payload{
+		Event:    event,
+		OD:       outputdir,
+		Step:     NSteps,
+		Time:     Time,
+		Quantity: quantity,
+		Files:    files,
+	}
+}
+
+// FireError runs the on-error hooks and sends the failure notification, waiting for both to return.
+func FireError(msg string) {
+	if runFailed {
+		return
+	}
+	runFailed = true
+	p := hookPayload(hooks.Error, "")
+	p.Error = msg
+	Hooks.Fire(p)
+	notifyEnd(notify.Failed, msg)
+	Hooks.Wait()
+}
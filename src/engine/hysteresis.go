@@ -0,0 +1,97 @@
+package engine
+
+// Hysteresis loops: B_ext is stepped along a fixed direction and the equilibrium is found at every point.
+
+import (
+	"strings"
+	"time"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/zarr"
+)
+
+var hysteresisSnapshots = false
+
+func init() {
+	DeclFunc("Hysteresis", hysteresis, "Hysteresis(name, direction, amplitudes, method) sets B_ext = amplitude*direction "+
+		"for each amplitude (T), finds the equilibrium with method \"relax\" or \"minimize\" and writes m, the energy "+
+		"and convergence info of every point to the zarr group name")
+	declVar("HysteresisSnapshots", &hysteresisSnapshots, "Whether Hysteresis() also saves m at every point, in name/m")
+}
+
+// hysteresisColumn is one array of the hysteresis group, with one value per field amplitude.
+type hysteresisColumn struct {
+	name, unit string
+	values     []float64
+}
+
+func hysteresis(name string, direction data.Vector, amplitudes InputArray, method string) {
+	var equilibrate func()
+	switch strings.ToLower(method) {
+	case "relax":
+		equilibrate = relax
+	case "minimize":
+		equilibrate = minimize
+	default:
+		log.Log.ErrAndExit("Hysteresis: unknown method `%s`, expected \"relax\" or \"minimize\"", method)
+	}
+	if direction.Len() == 0 {
+		log.Log.ErrAndExit("Hysteresis: the field direction cannot be zero")
+	}
+	if amplitudes.Len() == 0 {
+		log.Log.Warn("Hysteresis %s: no amplitudes given. Ignoring.", name)
+		return
+	}
+	direction = direction.Div(direction.Len())
+
+	columns := []*hysteresisColumn{
+		{name: "B", unit: "T"}, {name: "mx"}, {name: "my"}, {name: "mz"}, {name: "E_total", unit: "J"},
+		{name: "max_torque", unit: "T"}, {name: "steps"}, {name: "walltime", unit: "s"}, {name: "converged"},
+	}
+	units := make(map[string]string)
+	for _, c := range columns {
+		units[c.name] = c.unit
+	}
+	zarr.InitZgroup(name, OD())
+	zarr.SaveZattrs(OD()+name, map[string]any{"direction": direction, "method": strings.ToLower(method), "units": units})
+
+	for i, b := range amplitudes.data {
+		BExt.Set(direction.Mul(b))
+		start, steps0 := time.Now(), NSteps
+		equilibrate()
+		m := NormMag.Average()
+		maxTorque := getMaxTorque()
+		converged := hysteresisConverged(method, maxTorque)
+		row := []float64{b, m[X], m[Y], m[Z], getTotalEnergy(), maxTorque,
+			float64(NSteps - steps0), time.Since(start).Seconds(), boolToFloat(converged)}
+		for c, v := range row {
+			columns[c].values = append(columns[c].values, v)
+			// rewritten at every point, so that an interrupted loop leaves valid data
+			zarr.SaveFloat64Array(OD()+name+"/"+columns[c].name, columns[c].values)
+		}
+		if hysteresisSnapshots {
+			savedQuantities.saveAs(&NormMag, name+"/m")
+		}
+		if !converged {
+			log.Log.Warn("Hysteresis %s: point %d (B = %g T) did not converge, max torque = %g T", name, i, b, maxTorque)
+		}
+		log.Log.Info("Hysteresis %s: %d/%d, B = %g T, m = (%.4f, %.4f, %.4f)", name, i+1, amplitudes.Len(), b, m[X], m[Y], m[Z])
+	}
+}
+
+// hysteresisConverged tells whether the last relax or minimize stopped on its own convergence criterion
+// rather than on a step or time limit.
+func hysteresisConverged(method string, maxTorque float64) bool {
+	if strings.ToLower(method) == "minimize" {
+		return NSteps <= MinimizeTimeoutStep && int(time.Since(MinimizeStartTime).Seconds()) <= minimizeMaxTimeSeconds
+	}
+	return relaxTorqueThreshold <= 0 || maxTorque <= relaxTorqueThreshold
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,350 @@
+package engine
+
+// Landau-Lifshitz-Bloch equation for the reduced magnetization m, whose length relaxes to the mean-field
+// equilibrium me(T) at the temperature of each cell (Temp, or the heat equation):
+//
+//	dm/dt = γ/(1+λ²) [-m×B - α⊥/m² m×(m×B) + α∥/m² (m·B) m],   B = B_eff + B_long
+//
+// with the coupling to the bath λ = alpha, α∥ = λ 2T/(3Tc), α⊥ = λ (1 - T/(3Tc)) below Tc and α∥ above, and
+// the longitudinal field B_long = (1 - m²/me²) m/(2χ∥) below Tc and -(1 + 3Tc m²/(5(T-Tc))) m/χ∥ above.
+// me(T) and the longitudinal susceptibility χ∥(T) follow the mean-field model of classical spins with the
+// atomic moment MuAtom. The solvers do not normalize m in this mode.
+
+import (
+	"math"
+
+	"github.com/MathieuMoalic/amumax/src/cuda"
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/mag"
+)
+
+var (
+	Tc     = newScalarParam("Tc", "K", "Curie temperature (LLB)", &llbCoef)
+	MuAtom = newScalarParam("MuAtom", "J/T", "Atomic magnetic moment (LLB)", &llbCoef)
+	BLong  = newVectorField("B_long", "T", "Longitudinal field of the Landau-Lifshitz-Bloch equation", addLongitudinalField)
+	LLBMe  = newScalarField("m_e", "", "Equilibrium length of m at the temperature (LLB)", llbEquilibrium)
+
+	llbMode = false
+	llbCoef llbCoefficients
+)
+
+func init() {
+	declVar("LLB", &llbMode, "Uses the Landau-Lifshitz-Bloch equation, m is not normalized and its length relaxes "+
+		"to its equilibrium at the temperature (default=false)")
+	Temp.children = append(Temp.children, &llbCoef)
+}
+
+// llbCoefficients are the per-cell coefficients of the LLB equation, interpolated from llbTable and updated
+// when Temp, Tc or MuAtom change, or when the heat equation moves the temperature.
+type llbCoefficients struct {
+	coef        *data.Slice // components: a and b of B_long = (a + b m²) m, α⊥ and α∥
+	me          *data.Slice // equilibrium length of m
+	regions     lut         // the coefficients and me per region, when the temperature is uniform in each region
+	heat        bool        // computed from the temperature of the heat equation
+	heatVersion int         // version of that temperature
+	ok          bool
+}
+
+const (
+	llbA = iota
+	llbB
+	llbPerp
+	llbPar
+	llbMe // only in the table per region
+)
+
+func (c *llbCoefficients) invalidate() { c.ok = false }
+
+// upToDate returns whether the coefficients match the parameters and the temperature.
+func (c *llbCoefficients) upToDate() bool {
+	// bring the parameters up to date, which invalidates c if they changed
+	Temp.cpuLUT()
+	Tc.cpuLUT()
+	MuAtom.cpuLUT()
+	if !c.ok || c.coef == nil || c.coef.Size() != GetMesh().Size() || c.heat != heat.active() {
+		return false
+	}
+	return !c.heat || c.heatVersion == heat.version
+}
+
+// update recomputes the coefficients if they are not up to date. They are computed per region and decoded on
+// the GPU, unless the temperature, Tc or MuAtom vary within a region.
+func (c *llbCoefficients) update() {
+	if c.upToDate() {
+		return
+	}
+	size := GetMesh().Size()
+	if c.coef == nil || c.coef.Size() != size {
+		if c.coef != nil {
+			c.coef.Free()
+			c.me.Free()
+		}
+		c.coef = cuda.NewSlice(4, size)
+		c.me = cuda.NewSlice(1, size)
+	}
+	if Tc.isZero() || MuAtom.isZero() {
+		log.Log.ErrAndExit("LLB needs the Curie temperature Tc and the atomic moment MuAtom")
+	}
+
+	if heat.active() || Temp.cellMap != nil || Tc.cellMap != nil || MuAtom.cellMap != nil {
+		c.updateCells()
+	} else {
+		c.updateRegions()
+	}
+	c.heat = heat.active()
+	c.heatVersion = heat.version
+	c.ok = true
+}
+
+// updateRegions computes the coefficients per region and decodes them on the GPU.
+func (c *llbCoefficients) updateRegions() {
+	if c.regions.cpuBuf == nil {
+		c.regions.init(5, fixedLUT{})
+	}
+	temp, tc, mu := Temp.cpuLUT()[0], Tc.cpuLUT()[0], MuAtom.cpuLUT()[0]
+	v := c.regions.cpuBuf
+	for r := 0; r < NREGION; r++ {
+		v[llbA][r], v[llbB][r], v[llbPerp][r], v[llbPar][r], v[llbMe][r] = llbCellCoefficients(temp[r], tc[r], mu[r])
+	}
+	c.regions.gpuOk = false
+	gpu := c.regions.gpuLUT()
+	for k := llbA; k <= llbPar; k++ {
+		cuda.RegionDecode(c.coef.Comp(k), cuda.LUTPtr(gpu[k]), Regions.Gpu())
+	}
+	cuda.RegionDecode(c.me, cuda.LUTPtr(gpu[llbMe]), Regions.Gpu())
+}
+
+// updateCells computes the coefficients per cell on the CPU.
+func (c *llbCoefficients) updateCells() {
+	size := GetMesh().Size()
+	temp := hostValues(evalTemperature)
+	tc := hostValues(Tc.EvalTo)
+	mu := hostValues(MuAtom.EvalTo)
+	coef := data.NewSlice(4, size)
+	me := data.NewSlice(1, size)
+	a, b, perp, par := coef.Host()[llbA], coef.Host()[llbB], coef.Host()[llbPerp], coef.Host()[llbPar]
+	for i := range temp {
+		a[i], b[i], perp[i], par[i], me.Host()[0][i] = llbCellCoefficients(temp[i], tc[i], mu[i])
+	}
+	data.Copy(c.coef, coef)
+	data.Copy(c.me, me)
+}
+
+// llbCellCoefficients returns a and b of B_long, α⊥, α∥ and me at the temperature temp, for the Curie
+// temperature tc and the atomic moment mu. They are all 0 outside the magnet, or in a non-magnetic region.
+func llbCellCoefficients(temp, tc, mu float32) (a, b, perp, par, me float32) {
+	if tc <= 0 || mu <= 0 {
+		return 0, 0, 0, 0, 0
+	}
+	Tci := float64(tc)
+	tau := llbReducedTemp(float64(temp) / Tci)
+	T := tau * Tci
+	m2, g := llbTable.at(tau)
+	// 1/χ∥ = kT/μ τ²/g
+	invChi := mag.Kb * T / float64(mu) * g / (tau * tau)
+	par = float32(2 * tau / 3)
+	if tau < 1 {
+		return float32(invChi / 2), float32(-invChi / (2 * m2)), float32(1 - tau/3), par, float32(math.Sqrt(m2))
+	}
+	return float32(-invChi), float32(-3 * Tci / (5 * (T - Tci)) * invChi), par, par, 0
+}
+
+// fixedLUT is the source of a look-up table that is filled directly.
+type fixedLUT struct{}
+
+func (fixedLUT) update() {}
+
+// hostValues returns the values per cell of a scalar evaluated on the GPU.
+func hostValues(eval func(dst *data.Slice)) []float32 {
+	buf := cuda.Buffer(1, GetMesh().Size())
+	defer cuda.Recycle(buf)
+	eval(buf)
+	return buf.HostCopy().Host()[0]
+}
+
+// llbTable holds me² and g = τ²/(dm/dB kT/μ) of the mean-field model below Tc, on a uniform grid of the
+// reduced temperature τ from 0 to 1. Both are smooth and go to 0 linearly at Tc, so that they interpolate
+// well where me and χ∥ do not.
+var llbTable = newMeanFieldTable(4096)
+
+type meanFieldTable struct {
+	m2, g []float64
+}
+
+func newMeanFieldTable(n int) *meanFieldTable {
+	t := &meanFieldTable{m2: make([]float64, n+1), g: make([]float64, n+1)}
+	t.m2[0], t.g[0] = 1, 9 // me → 1 and g → 9 me² as τ → 0
+	for i := 1; i < n; i++ {
+		tau := float64(i) / float64(n)
+		m := meanFieldM(tau)
+		t.m2[i] = m * m
+		t.g[i] = tau * tau / longitudinalSusceptibility(tau, m, mag.Kb, 1) // μ = k and T = 1 leave dm/dB kT/μ
+	}
+	return t
+}
+
+// at returns me² and g at the reduced temperature tau, which is the Curie-Weiss τ²/(τ/(3(τ-1))) above Tc.
+func (t *meanFieldTable) at(tau float64) (m2, g float64) {
+	if tau >= 1 {
+		return 0, 3 * tau * (tau - 1)
+	}
+	n := len(t.m2) - 1
+	x := tau * float64(n)
+	i := min(int(x), n-1)
+	f := x - float64(i)
+	return t.m2[i] + f*(t.m2[i+1]-t.m2[i]), t.g[i] + f*(t.g[i+1]-t.g[i])
+}
+
+// llbReducedTemp keeps T/Tc away from 0 and 1, where the susceptibility is 0 or diverges.
+func llbReducedTemp(tau float64) float64 {
+	const eps = 1e-3
+	switch {
+	case tau < eps:
+		return eps
+	case math.Abs(tau-1) < eps && tau < 1:
+		return 1 - eps
+	case math.Abs(tau-1) < eps:
+		return 1 + eps
+	}
+	return tau
+}
+
+// langevin returns L(x) = coth(x) - 1/x and its derivative.
+func langevin(x float64) (float64, float64) {
+	if math.Abs(x) < 1e-3 {
+		return x/3 - x*x*x/45, 1./3 - x*x/15
+	}
+	s := math.Sinh(x)
+	return 1/math.Tanh(x) - 1/x, 1/(x*x) - 1/(s*s)
+}
+
+// meanFieldM returns the solution of m = L(3m/τ) at the reduced temperature τ = T/Tc, 0 above Tc.
+func meanFieldM(tau float64) float64 {
+	if tau >= 1 {
+		return 0
+	}
+	lo, hi := 0.0, 1.0
+	for i := 0; i < 60; i++ {
+		m := (lo + hi) / 2
+		if l, _ := langevin(3 * m / tau); l > m {
+			lo = m
+		} else {
+			hi = m
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// longitudinalSusceptibility returns dm/dB (1/T) of the mean-field model at the reduced temperature tau
+// and the equilibrium m, for the atomic moment mu (J/T) and the temperature T (K).
+func longitudinalSusceptibility(tau, m, mu, T float64) float64 {
+	_, dl := langevin(3 * m / tau)
+	return mu / (mag.Kb * T) * dl / (1 - 3*dl/tau)
+}
+
+// addLongitudinalField adds B_long = (a + b m²) m to dst.
+func addLongitudinalField(dst *data.Slice) {
+	if !llbMode {
+		return
+	}
+	llbCoef.update()
+	m := NormMag.Buffer()
+	k := cuda.Buffer(1, m.Size())
+	defer cuda.Recycle(k)
+	tmp := cuda.Buffer(1, m.Size())
+	defer cuda.Recycle(tmp)
+	cuda.Zero(k)
+	cuda.AddDotProduct(k, 1, m, m)
+	cuda.Mul(k, k, llbCoef.coef.Comp(llbB))
+	cuda.Add(k, k, llbCoef.coef.Comp(llbA))
+	for c := 0; c < 3; c++ {
+		cuda.Mul(tmp, k, m.Comp(c))
+		cuda.Add(dst.Comp(c), dst.Comp(c), tmp)
+	}
+}
+
+func llbEquilibrium(dst *data.Slice) {
+	if !llbMode {
+		cuda.Memset(dst, 1)
+		return
+	}
+	llbCoef.update()
+	data.Copy(dst, llbCoef.me)
+}
+
+// setLLBTorque sets dst to the LLB torque/γ0.
+func setLLBTorque(dst *data.Slice) {
+	if Aex.cellMap != nil {
+		log.Log.ErrAndExit("LLB does not support a map on Aex")
+	}
+	setEffectiveField(dst)
+	addLongitudinalField(dst)
+	m := NormMag.Buffer()
+	size := m.Size()
+	b := cuda.Buffer(3, size)
+	defer cuda.Recycle(b)
+	data.Copy(b, dst)
+
+	// precession -m×B
+	if precess {
+		cuda.LLTorque(dst, m, b, cuda.MakeMSlice(data.NilSlice(1, size), []float64{0}))
+	} else {
+		cuda.Zero(dst)
+	}
+
+	alpha := cuda.Buffer(1, size)
+	defer cuda.Recycle(alpha)
+	Alpha.EvalTo(alpha)
+	m2 := cuda.Buffer(1, size)
+	defer cuda.Recycle(m2)
+	cuda.Zero(m2)
+	cuda.AddDotProduct(m2, 1, m, m)
+	scale := cuda.Buffer(1, size)
+	defer cuda.Recycle(scale)
+
+	// transverse damping -λα⊥/m² m×(m×B)
+	damp := cuda.Buffer(3, size)
+	defer cuda.Recycle(damp)
+	cuda.LLNoPrecess(damp, m, b)
+	cuda.Mul(scale, alpha, llbCoef.coef.Comp(llbPerp))
+	cuda.Div(scale, scale, m2)
+	for c := 0; c < 3; c++ {
+		cuda.Mul(damp.Comp(c), damp.Comp(c), scale)
+		cuda.Add(dst.Comp(c), dst.Comp(c), damp.Comp(c))
+	}
+
+	// longitudinal damping λα∥/m² (m·B) m
+	cuda.Mul(scale, alpha, llbCoef.coef.Comp(llbPar))
+	cuda.Div(scale, scale, m2)
+	mb := cuda.Buffer(1, size)
+	defer cuda.Recycle(mb)
+	cuda.Zero(mb)
+	cuda.AddDotProduct(mb, 1, m, b)
+	cuda.Mul(scale, scale, mb)
+	for c := 0; c < 3; c++ {
+		cuda.Mul(damp.Comp(c), m.Comp(c), scale)
+		cuda.Add(dst.Comp(c), dst.Comp(c), damp.Comp(c))
+	}
+
+	// 1/(1+λ²)
+	cuda.Mul(scale, alpha, alpha)
+	cuda.Memset(m2, 1)
+	cuda.Add(scale, scale, m2)
+	cuda.Div(scale, m2, scale)
+	for c := 0; c < 3; c++ {
+		cuda.Mul(dst.Comp(c), dst.Comp(c), scale)
+	}
+}
+
+// keepInGeometry zeroes m outside the geometry, without normalizing it.
+func keepInGeometry(m *data.Slice) {
+	if Geometry.Gpu().IsNil() {
+		return
+	}
+	inside := insideGeometry()
+	defer cuda.Recycle(inside)
+	for c := 0; c < 3; c++ {
+		cuda.Mul(m.Comp(c), m.Comp(c), inside)
+	}
+}
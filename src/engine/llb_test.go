@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/MathieuMoalic/amumax/src/mag"
+)
+
+func TestLangevin(t *testing.T) {
+	for _, x := range []float64{-5, -0.5, 1e-4, 0.999e-3, 1.001e-3, 0.2, 3, 50} {
+		l, dl := langevin(x)
+		if want := 1/math.Tanh(x) - 1/x; math.Abs(l-want) > 1e-9 {
+			t.Errorf("L(%g) = %g, want %g", x, l, want)
+		}
+		const h = 1e-5
+		lp, _ := langevin(x + h)
+		lm, _ := langevin(x - h)
+		if want := (lp - lm) / (2 * h); math.Abs(dl-want) > 1e-6 {
+			t.Errorf("L'(%g) = %g, want %g", x, dl, want)
+		}
+	}
+}
+
+func TestMeanFieldM(t *testing.T) {
+	if m := meanFieldM(1e-3); math.Abs(m-1) > 1e-3 {
+		t.Errorf("me(τ → 0) = %g, want 1", m)
+	}
+	for _, tau := range []float64{1, 1.001, 2} {
+		if m := meanFieldM(tau); m != 0 {
+			t.Errorf("me(%g) = %g, want 0", tau, m)
+		}
+	}
+	prev := 1.0
+	for _, tau := range []float64{0.1, 0.5, 0.9, 0.99} {
+		m := meanFieldM(tau)
+		if l, _ := langevin(3 * m / tau); math.Abs(l-m) > 1e-9 {
+			t.Errorf("me(%g) = %g does not solve m = L(3m/τ)", tau, m)
+		}
+		if m <= 0 || m >= prev {
+			t.Errorf("me(%g) = %g, should decrease from 1 to 0", tau, m)
+		}
+		prev = m
+	}
+}
+
+func TestLongitudinalSusceptibility(t *testing.T) {
+	const mu, Tc = 2e-23, 600.
+
+	// Curie-Weiss above Tc
+	for _, tau := range []float64{1.01, 1.5, 3} {
+		T := tau * Tc
+		chi := longitudinalSusceptibility(tau, meanFieldM(tau), mu, T)
+		if want := mu / (3 * mag.Kb * (T - Tc)); math.Abs(chi-want) > 1e-9*want {
+			t.Errorf("χ(%g K) = %g, want %g", T, chi, want)
+		}
+	}
+
+	// below Tc, dm/dB of the mean-field solution of m = L(μ(B + Bmf)/kT) with Bmf = 3kTc m/μ
+	for _, tau := range []float64{0.3, 0.8, 0.95} {
+		T := tau * Tc
+		m0 := meanFieldM(tau)
+		chi := longitudinalSusceptibility(tau, m0, mu, T)
+		const dB = 1e-3
+		solve := func(B float64) float64 {
+			lo, hi := 0.0, 1.0
+			for i := 0; i < 60; i++ {
+				m := (lo + hi) / 2
+				if l, _ := langevin(mu*B/(mag.Kb*T) + 3*m/tau); l > m {
+					lo = m
+				} else {
+					hi = m
+				}
+			}
+			return (lo + hi) / 2
+		}
+		want := (solve(dB) - solve(-dB)) / (2 * dB)
+		if math.Abs(chi-want) > 1e-3*want {
+			t.Errorf("χ(%g K) = %g, want %g", T, chi, want)
+		}
+	}
+}
+
+// The coefficients interpolated from the table match those of the exact mean-field solution.
+func TestLLBCellCoefficients(t *testing.T) {
+	const mu, Tc = 2e-23, 600.
+	for _, tau := range []float64{1e-3, 0.01, 0.3, 0.77, 0.95, 0.999, 1.001, 1.5} {
+		T := tau * Tc
+		a, b, perp, par, me := llbCellCoefficients(float32(T), Tc, mu)
+		m0 := meanFieldM(tau)
+		chi := longitudinalSusceptibility(tau, m0, mu, T)
+		wantA, wantB, wantPerp := 1/(2*chi), -1/(2*chi*m0*m0), 1-tau/3
+		if tau > 1 {
+			wantA, wantB, wantPerp = -1/chi, -3*Tc/(5*chi*(T-Tc)), 2*tau/3
+		}
+		check := func(name string, got float32, want float64) {
+			if math.Abs(float64(got)-want) > 1e-3*math.Abs(want)+1e-6 {
+				t.Errorf("τ = %g: %s = %g, want %g", tau, name, got, want)
+			}
+		}
+		check("a", a, wantA)
+		check("b", b, wantB)
+		check("α⊥", perp, wantPerp)
+		check("α∥", par, 2*tau/3)
+		check("me", me, m0)
+	}
+	if a, b, perp, par, me := llbCellCoefficients(300, 0, mu); a != 0 || b != 0 || perp != 0 || par != 0 || me != 0 {
+		t.Error("coefficients in a region without Tc")
+	}
+}
@@ -37,6 +37,15 @@ func (m *magnetization) average() []float64      { return sAverageMagnet(NormMag
 func (m *magnetization) Average() data.Vector    { return unslice(m.average()) }
 func (m *magnetization) normalize()              { cuda.Normalize(m.Buffer(), Geometry.Gpu()) }
 
+// renormalize normalizes m after it was evolved, except in the LLB mode where its length is free.
+func (m *magnetization) renormalize() {
+	if llbMode {
+		keepInGeometry(m.Buffer())
+		return
+	}
+	m.normalize()
+}
+
 // Alloc allocate storage (not done by init, as mesh size may not yet be known then)
 func (m *magnetization) Alloc() {
 	m.buffer = cuda.NewSlice(3, m.Mesh().Size())
@@ -51,6 +60,15 @@ func (m *magnetization) SetArray(src *data.Slice) {
 	m.normalize()
 }
 
+// restore sets m back to a copy taken earlier, keeping its length in LLB mode.
+func (m *magnetization) restore(src *data.Slice) {
+	if src.Size() != m.Mesh().Size() {
+		src = data.Resample(src, m.Mesh().Size())
+	}
+	data.Copy(m.Buffer(), src)
+	m.renormalize()
+}
+
 func (m *magnetization) Set(c config) {
 	m.SetInShape(nil, c)
 }
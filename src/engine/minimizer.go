@@ -124,6 +124,9 @@ var (
 )
 
 func minimize() {
+	if llbMode {
+		log.Log.ErrAndExit("Minimize assumes |m| = 1, set LLB = false first")
+	}
 	checkExchangeLength()
 	MinimizeStartTime = time.Now()
 	MinimizeTimeoutStep = NSteps + minimizeMaxSteps
@@ -0,0 +1,21 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/notify"
+)
+
+// Notifier sends a notification when the run finishes or fails, nil disables notifications.
+var Notifier *notify.Notifiers
+
+func notifyEnd(event notify.Event, errMsg string) {
+	if Notifier == nil {
+		return
+	}
+	m := notify.NewMessage(event, InputFile, outputdir, time.Since(StartTime))
+	m.Error = errMsg
+	m.LogTail = notify.Tail(log.Log.Hist, 20)
+	Notifier.Send(m)
+}
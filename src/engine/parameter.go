@@ -29,6 +29,9 @@ type regionwise struct {
 	timestamp  float64                   // used not to double-evaluate f(t)
 	children   []derived                 // derived parameters
 	name, unit string
+	cellMap    *cellMap        // cell-wise factor, nil if the parameter is uniform within regions
+	noMap      bool            // the parameter is only used per region and cannot have a map
+	tempDep    *tempDependence // temperature law, nil if the parameter does not depend on Temp
 }
 
 var Params map[string]field
@@ -37,6 +40,7 @@ type field struct {
 	Name        string           `json:"name"`
 	Value       func(int) string `json:"value"`
 	Description string           `json:"description"`
+	Map         func() string    `json:"-"` // source of the cell-wise map, nil if the parameter cannot have one
 }
 
 func addParameter(name string, value any, doc string) {
@@ -48,30 +52,35 @@ func addParameter(name string, value any, doc string) {
 			name,
 			v.GetRegionToString,
 			doc,
+			v.mapSource,
 		}
 	} else if v, ok := value.(*regionwiseVector); ok {
 		Params[name] = field{
 			name,
 			v.GetRegionToString,
 			doc,
+			nil,
 		}
 	} else if v, ok := value.(*inputValue); ok {
 		Params[name] = field{
 			name,
 			v.GetRegionToString,
 			doc,
+			nil,
 		}
 	} else if v, ok := value.(*excitation); ok {
 		Params[name] = field{
 			name,
 			v.GetRegionToString,
 			doc,
+			nil,
 		}
 	} else if v, ok := value.(*scalarExcitation); ok {
 		Params[name] = field{
 			name,
 			v.GetRegionToString,
 			doc,
+			nil,
 		}
 	}
 }
@@ -93,6 +102,18 @@ func (p *regionwise) MSlice() cuda.MSlice {
 	return cuda.ToMSlice(buf)
 }
 
+// Slice returns the parameter per cell, multiplied by its map if it has one.
+func (p *regionwise) Slice() (*data.Slice, bool) {
+	b := cuda.Buffer(p.NComp(), GetMesh().Size())
+	p.EvalTo(b)
+	return b, true
+}
+
+func (p *regionwise) EvalTo(dst *data.Slice) {
+	p.lut.EvalTo(dst)
+	p.applyMap(dst)
+}
+
 func (p *regionwise) Name() string     { return p.name }
 func (p *regionwise) Unit() string     { return p.unit }
 func (p *regionwise) Mesh() *mesh.Mesh { return GetMesh() }
@@ -113,6 +134,9 @@ func (p *regionwise) update() {
 			p.invalidate()
 		}
 	}
+	if p.tempDep != nil {
+		p.updateTemp()
+	}
 }
 
 // set in one region
@@ -142,8 +166,12 @@ func (p *regionwise) setRegions(r1, r2 int, v []float64) {
 }
 
 func (p *regionwise) bufset(region int, v []float64) {
-	for c := range p.cpuBuf {
-		p.cpuBuf[c][region] = float32(v[c])
+	buf := p.cpuBuf
+	if p.tempDep != nil {
+		buf = p.tempDep.base
+	}
+	for c := range buf {
+		buf[c][region] = float32(v[c])
 	}
 }
 
@@ -159,6 +187,9 @@ func (p *regionwise) setFunc(r1, r2 int, f func() []float64) {
 // mark my GPU copy and my children as invalid (need update)
 func (p *regionwise) invalidate() {
 	p.gpuOk = false
+	if p.tempDep != nil {
+		p.tempDep.ok = false
+	}
 	for _, c := range p.children {
 		c.invalidate()
 	}
@@ -174,6 +205,9 @@ func (p *regionwise) getRegion(region int) []float64 {
 }
 
 func (p *regionwise) IsUniform() bool {
+	if p.cellMap != nil {
+		return false
+	}
 	cpu := p.cpuLUT()
 	v1 := p.getRegion(0)
 	for r := 1; r < NREGION; r++ {
@@ -0,0 +1,171 @@
+package engine
+
+// Cell-wise maps multiplying the region values of a parameter, for parameters that vary in space
+// within a region. Parameters with a map are passed to the kernels cell by cell.
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/MathieuMoalic/amumax/src/cuda"
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/log"
+)
+
+// cellMap is a scalar factor per cell on the GPU, and its square root for the exchange.
+type cellMap struct {
+	factor, sqrtFactor *data.Slice
+	source             string // where the map comes from, shown in the GUI
+}
+
+// SetMap multiplies the parameter in each cell by the values of a scalar array, resampled onto the mesh.
+func (p *regionwiseScalar) SetMap(s *data.Slice) {
+	p.checkMap("SetMap")
+	if s.NComp() != 1 {
+		log.Log.ErrAndExit("%s.SetMap needs a scalar array, this one has %d components", p.name, s.NComp())
+	}
+	if s.Size() != GetMesh().Size() {
+		log.Log.Info("%s.SetMap: resampling the array from %v to %v cells", p.name, s.Size(), GetMesh().Size())
+	}
+	p.setMap(data.Resample(s, GetMesh().Size()), fmt.Sprintf("array %v", s.Size()))
+}
+
+// SetMapFunc multiplies the parameter in each cell by the value of an expression of the cell center x, y, z (m),
+// e.g. Msat.SetMapFunc("1 - 0.5*exp(-(x*x+y*y)/(50e-9*50e-9))").
+func (p *regionwiseScalar) SetMapFunc(expr string) {
+	p.checkMap("SetMapFunc")
+	World.EnterScope()
+	var x, y, z float64
+	World.Var("x", &x)
+	World.Var("y", &y)
+	World.Var("z", &z)
+	code, err := World.CompileExpr(expr)
+	World.ExitScope()
+	if err != nil {
+		log.Log.ErrAndExit("%s.SetMapFunc: %v", p.name, err)
+	}
+	if k := code.Type().Kind(); k != reflect.Float64 && k != reflect.Int {
+		log.Log.ErrAndExit("%s.SetMapFunc: `%s` is not a number", p.name, expr)
+	}
+
+	host := data.NewSlice(1, GetMesh().Size())
+	values := host.Scalars()
+	n := GetMesh().Size()
+	for iz := 0; iz < n[Z]; iz++ {
+		for iy := 0; iy < n[Y]; iy++ {
+			for ix := 0; ix < n[X]; ix++ {
+				r := index2Coord(ix, iy, iz)
+				x, y, z = r[X], r[Y], r[Z]
+				values[iz][iy][ix] = float32(reflect.ValueOf(code.Eval()).Convert(reflect.TypeOf(0.0)).Float())
+			}
+		}
+	}
+	p.setMap(host, expr)
+}
+
+// checkMap exits if the parameter cannot have a map.
+func (p *regionwiseScalar) checkMap(method string) {
+	if p.noMap {
+		log.Log.ErrAndExit("%s.%s: %s cannot have a map, it is uniform in each region", p.name, method, p.name)
+	}
+}
+
+// ClearMap removes the map, the parameter is again uniform in each region.
+func (p *regionwiseScalar) ClearMap() {
+	if p.cellMap != nil {
+		p.cellMap.factor.Free()
+		p.cellMap.sqrtFactor.Free()
+		p.cellMap = nil
+		p.invalidate()
+	}
+}
+
+// mapSource returns where the map of the parameter comes from, or "" without a map.
+func (p *regionwise) mapSource() string {
+	if p.cellMap == nil {
+		return ""
+	}
+	return p.cellMap.source
+}
+
+func (p *regionwise) setMap(host *data.Slice, source string) {
+	values := host.Host()[0]
+	sqrtHost := data.NewSlice(1, host.Size())
+	sqrtValues := sqrtHost.Host()[0]
+	for i, v := range values {
+		if v < 0 || math.IsNaN(float64(v)) {
+			log.Log.ErrAndExit("The map of %s should not be negative, found %v", p.name, v)
+		}
+		sqrtValues[i] = float32(math.Sqrt(float64(v)))
+	}
+	if p.cellMap == nil {
+		p.cellMap = &cellMap{factor: cuda.NewSlice(1, host.Size()), sqrtFactor: cuda.NewSlice(1, host.Size())}
+	}
+	data.Copy(p.cellMap.factor, host)
+	data.Copy(p.cellMap.sqrtFactor, sqrtHost)
+	p.cellMap.source = source
+	p.invalidate()
+	QuantityChanged[p.name] = true
+	log.Log.Info("%s is multiplied by a map from %s", p.name, source)
+}
+
+// applyMap multiplies dst, with the values of the parameter per cell, by the map.
+func (p *regionwise) applyMap(dst *data.Slice) {
+	if p.cellMap == nil {
+		return
+	}
+	if p.cellMap.factor.Size() != dst.Size() {
+		log.Log.ErrAndExit("The map of %s was set for a mesh of %v cells, the mesh now has %v cells", p.name, p.cellMap.factor.Size(), dst.Size())
+	}
+	for c := 0; c < dst.NComp(); c++ {
+		cuda.Mul(dst.Comp(c), dst.Comp(c), p.cellMap.factor)
+	}
+}
+
+// addExchangeFieldMap adds the exchange field with the stiffness between cells i and j multiplied by
+// sqrt(s_i s_j) for the map s of Aex. With the exchange kernel B'(f)_i = Σ_j w A_ij (f_j - f_i) / Ms_i,
+// m' = sqrt(s) m and g = sqrt(s) |m| in every component:
+// sqrt(s_i) (B'(m')_i - m_i B'(g)_i) = Σ_j w A_ij sqrt(s_i s_j) (m_j - m_i) / Ms_i.
+func addExchangeFieldMap(dst *data.Slice, ms cuda.MSlice) {
+	m := NormMag.Buffer()
+	size := m.Size()
+	sqrtS := Aex.cellMap.sqrtFactor
+	if sqrtS.Size() != size {
+		log.Log.ErrAndExit("The map of Aex was set for a mesh of %v cells, the mesh now has %v cells", sqrtS.Size(), size)
+	}
+
+	scaled := cuda.Buffer(3, size)
+	defer cuda.Recycle(scaled)
+	g := cuda.Buffer(3, size)
+	defer cuda.Recycle(g)
+	tmp := cuda.Buffer(1, size)
+	defer cuda.Recycle(tmp)
+
+	// g = sqrt(s) |m|², |m| being 1 inside the magnet and 0 outside
+	cuda.Zero(g.Comp(0))
+	for c := 0; c < 3; c++ {
+		cuda.Mul(scaled.Comp(c), m.Comp(c), sqrtS)
+		cuda.Mul(tmp, m.Comp(c), m.Comp(c))
+		cuda.Madd2(g.Comp(0), g.Comp(0), tmp, 1, 1)
+	}
+	cuda.Mul(g.Comp(0), g.Comp(0), sqrtS)
+	data.Copy(g.Comp(1), g.Comp(0))
+	data.Copy(g.Comp(2), g.Comp(0))
+
+	bScaled := cuda.Buffer(3, size)
+	defer cuda.Recycle(bScaled)
+	bG := cuda.Buffer(3, size)
+	defer cuda.Recycle(bG)
+	cuda.Zero(bScaled)
+	cuda.Zero(bG)
+	cuda.AddExchange(bScaled, scaled, lex2.Gpu(), ms, Regions.Gpu(), NormMag.Mesh())
+	cuda.AddExchange(bG, g, lex2.Gpu(), ms, Regions.Gpu(), NormMag.Mesh())
+
+	for c := 0; c < 3; c++ {
+		cuda.Mul(tmp, m.Comp(c), bG.Comp(0))
+		cuda.Madd2(tmp, bScaled.Comp(c), tmp, 1, -1)
+		cuda.Mul(tmp, tmp, sqrtS)
+		cuda.Madd2(dst.Comp(c), dst.Comp(c), tmp, 1, 1)
+	}
+}
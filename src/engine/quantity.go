@@ -71,6 +71,15 @@ func unitOf(q Quantity) string {
 	return "?"
 }
 
+// TitleWithUnit returns title followed by the unit of q in parentheses, or title alone if q is
+// dimensionless or does not define a unit.
+func TitleWithUnit(title string, q Quantity) string {
+	if u := unitOf(q); u != "" && u != "?" {
+		return title + " (" + u + ")"
+	}
+	return title
+}
+
 func MeshOf(q Quantity) *mesh.Mesh {
 	// quantity defines its own, custom, implementation:
 	if s, ok := q.(interface {
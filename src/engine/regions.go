@@ -52,9 +52,13 @@ func (rs *RegionsState) redefine(startID, endID int) {
 	rs.gpuBuffer.Upload(l)
 }
 
+// ShapeFromRegion returns the cells of region id, as they are now. It can be combined with other shapes
+// like any shape, e.g. to define a region from the union or intersection of others.
 func ShapeFromRegion(id int) shape {
+	regions := Regions.RegionListCPU()
 	return func(x, y, z float64) bool {
-		return Regions.get(data.Vector{x, y, z}) == id
+		i, _, ok := meshCell(x, y, z)
+		return ok && int(regions[i]) == id
 	}
 }
 
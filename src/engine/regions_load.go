@@ -0,0 +1,147 @@
+package engine
+
+// Region maps from labelled images or arrays, e.g. grains segmented from microscopy.
+
+import (
+	"bufio"
+	"image"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/fsutil"
+	"github.com/MathieuMoalic/amumax/src/log"
+)
+
+func init() {
+	DeclFunc("LoadLabelImage", loadLabelImage, "Loads the integer labels of a PNG image with a palette (palette "+
+		"index) or in 8/16-bit grayscale (gray value) as a scalar array")
+	DeclFunc("DefRegionsFromArray", defRegionsFromArray, "Sets the region of every cell from an array of integer "+
+		"labels (first argument), resampled onto the mesh. The labels are mapped to regions by the lookup table file "+
+		"(second argument) with one `label region` pair per line, or used as regions if it is \"\"")
+	DeclFunc("DefRegionsFromImage", defRegionsFromImage, "Sets the region of every cell from a labelled image, "+
+		"like DefRegionsFromArray(LoadLabelImage(file), lookupTable)")
+}
+
+func loadLabelImage(fname string) *data.Slice {
+	img := decodeImage(fname)
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	var label func(x, y int) float32
+	switch img := img.(type) {
+	case *image.Paletted:
+		label = func(x, y int) float32 { return float32(img.ColorIndexAt(x, y)) }
+	case *image.Gray:
+		label = func(x, y int) float32 { return float32(img.GrayAt(x, y).Y) }
+	case *image.Gray16:
+		label = func(x, y int) float32 { return float32(img.Gray16At(x, y).Y) }
+	default:
+		log.Log.ErrAndExit("LoadLabelImage: %s should have a palette or be 8/16-bit grayscale, it is %T", fname, img)
+	}
+	labels := data.NewSlice(1, [3]int{width, height, 1})
+	layer := labels.Scalars()[0]
+	origin := img.Bounds().Min
+	for iy := 0; iy < height; iy++ {
+		for ix := 0; ix < width; ix++ {
+			// the first row of the image is the top, at the largest y
+			layer[iy][ix] = label(origin.X+ix, origin.Y+height-1-iy)
+		}
+	}
+	return labels
+}
+
+func defRegionsFromImage(fname, lookupTable string) {
+	defRegionsFromArray(loadLabelImage(fname), lookupTable)
+}
+
+func defRegionsFromArray(labels *data.Slice, lookupTable string) {
+	if labels.NComp() != 1 {
+		log.Log.ErrAndExit("DefRegionsFromArray needs a scalar array, this one has %d components", labels.NComp())
+	}
+	var lut map[int]int
+	if lookupTable != "" {
+		lut = readRegionLUT(lookupTable)
+	}
+
+	values := data.Resample(labels, GetMesh().Size()).Host()[0]
+	regions := Regions.RegionListCPU()
+	used := make(map[int]bool)
+	unmapped := make(map[int]bool)
+	for i, v := range values {
+		label := int(v)
+		if float64(label) != float64(v) {
+			log.Log.ErrAndExit("DefRegionsFromArray: the labels should be integers, found %v", v)
+		}
+		region := label
+		if lut != nil {
+			r, ok := lut[label]
+			if !ok {
+				// cells with a label missing from the table keep their region
+				unmapped[label] = true
+				continue
+			}
+			region = r
+		} else if label < 0 || label >= NREGION {
+			log.Log.ErrAndExit("DefRegionsFromArray: label %d is not a valid region (0-%d), use a lookup table", label, NREGION-1)
+		}
+		regions[i] = byte(region)
+		used[region] = true
+	}
+	Regions.gpuBuffer.Upload(regions)
+	for r := range used {
+		Regions.AddIndex(r)
+	}
+	if len(unmapped) > 0 {
+		log.Log.Warn("DefRegionsFromArray: labels %v are not in %s, their cells keep their region", sortedKeys(unmapped), lookupTable)
+	}
+	log.Log.Info("Defined %d regions from labels", len(used))
+}
+
+// readRegionLUT reads a lookup table with a `label region` pair on each line. Empty lines and lines starting
+// with # are skipped.
+func readRegionLUT(fname string) map[int]int {
+	r, err := fsutil.Open(fname)
+	log.Log.PanicIfError(err)
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Log.PanicIfError(err)
+		}
+	}()
+	lut := make(map[int]int)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			log.Log.ErrAndExit("%s:%d: expected `label region`, got `%s`", fname, line, text)
+		}
+		label, err1 := strconv.Atoi(fields[0])
+		region, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			log.Log.ErrAndExit("%s:%d: label and region should be integers, got `%s`", fname, line, text)
+		}
+		if region < 0 || region >= NREGION {
+			log.Log.ErrAndExit("%s:%d: region %d is not valid, regions are 0-%d", fname, line, region, NREGION-1)
+		}
+		if _, ok := lut[label]; ok {
+			log.Log.ErrAndExit("%s:%d: label %d is already in the table", fname, line, label)
+		}
+		lut[label] = region
+	}
+	log.Log.PanicIfError(scanner.Err())
+	return lut
+}
+
+func sortedKeys(m map[int]bool) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
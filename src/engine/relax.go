@@ -6,6 +6,7 @@ import (
 	"math"
 
 	"github.com/MathieuMoalic/amumax/src/cuda"
+	"github.com/MathieuMoalic/amumax/src/log"
 )
 
 // Stopping relax Maxtorque in T. The user can check MaxTorque for sane values (e.g. 1e-3).
@@ -16,6 +17,9 @@ var relaxTorqueThreshold float64 = -1.
 var relaxing = false
 
 func relax() {
+	if llbMode {
+		log.Log.ErrAndExit("Relax assumes |m| = 1, set LLB = false first")
+	}
 	checkExchangeLength()
 	sanityCheck()
 	Pause = false
@@ -42,7 +42,7 @@ func (rk *rk23) Step() {
 	}
 
 	// FSAL cannot be used with temperature
-	if !Temp.isZero() {
+	if !zeroTemperature() {
 		torqueFn(rk.k1)
 	}
 
@@ -64,18 +64,18 @@ func (rk *rk23) Step() {
 	// stage 2
 	Time = t0 + (1./2.)*DtSi
 	cuda.Madd2(m, m, rk.k1, 1, (1./2.)*h) // m = m*1 + k1*h/2
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k2)
 
 	// stage 3
 	Time = t0 + (3./4.)*DtSi
 	cuda.Madd2(m, m0, k2, 1, (3./4.)*h) // m = m0*1 + k2*3/4
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k3)
 
 	// 3rd order solution
 	cuda.Madd4(m, m0, rk.k1, k2, k3, 1, (2./9.)*h, (1./3.)*h, (4./9.)*h)
-	NormMag.normalize()
+	NormMag.renormalize()
 
 	// error estimate
 	Time = t0 + DtSi
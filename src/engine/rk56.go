@@ -43,50 +43,50 @@ func (rk *rk56) Step() {
 	// stage 2
 	Time = t0 + (1./6.)*DtSi
 	cuda.Madd2(m, m, k1, 1, (1./6.)*h) // m = m*1 + k1*h/6
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k2)
 
 	// stage 3
 	Time = t0 + (4./15.)*DtSi
 	cuda.Madd3(m, m0, k1, k2, 1, (4./75.)*h, (16./75.)*h)
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k3)
 
 	// stage 4
 	Time = t0 + (2./3.)*DtSi
 	cuda.Madd4(m, m0, k1, k2, k3, 1, (5./6.)*h, (-8./3.)*h, (5./2.)*h)
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k4)
 
 	// stage 5
 	Time = t0 + (4./5.)*DtSi
 	cuda.Madd5(m, m0, k1, k2, k3, k4, 1, (-8./5.)*h, (144./25.)*h, (-4.)*h, (16./25.)*h)
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k5)
 
 	// stage 6
 	Time = t0 + (1.)*DtSi
 	cuda.Madd6(m, m0, k1, k2, k3, k4, k5, 1, (361./320.)*h, (-18./5.)*h, (407./128.)*h, (-11./80.)*h, (55./128.)*h)
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k6)
 
 	// stage 7
 	Time = t0
 	cuda.Madd5(m, m0, k1, k3, k4, k5, 1, (-11./640.)*h, (11./256.)*h, (-11/160.)*h, (11./256.)*h)
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k7)
 
 	// stage 8
 	Time = t0 + (1.)*DtSi
 	cuda.Madd7(m, m0, k1, k2, k3, k4, k5, k7, 1, (93./640.)*h, (-18./5.)*h, (803./256.)*h, (-11./160.)*h, (99./256.)*h, (1.)*h)
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k8)
 
 	// stage 9: 6th order solution
 	Time = t0 + (1.)*DtSi
 	// madd6(m, m0, k1, k3, k4, k5, k6, 1, (31./384.)*h, (1125./2816.)*h, (9./32.)*h, (125./768.)*h, (5./66.)*h)
 	cuda.Madd7(m, m0, k1, k3, k4, k5, k7, k8, 1, (7./1408.)*h, (1125./2816.)*h, (9./32.)*h, (125./768.)*h, (5./66.)*h, (5./66.)*h)
-	NormMag.normalize()
+	NormMag.renormalize()
 	torqueFn(k2) // re-use k2
 
 	// error estimate
@@ -0,0 +1,223 @@
+package engine
+
+// Gaussian-correlated random edges and surfaces. The profiles have the autocorrelation rms² exp(-r²/ξ²)
+// for a correlation length ξ, are sampled at the cell centers and interpolated linearly in between.
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/zarr"
+)
+
+func init() {
+	DeclFunc("EdgeRoughStrip", edgeRoughStrip, "EdgeRoughStrip(name, width, rms, corrLength, seed) is a strip along x "+
+		"of mean width (m) whose two edges have independent Gaussian-correlated roughness. The edge profiles are "+
+		"written to the zarr group name")
+	DeclFunc("RoughFilm", roughFilm, "RoughFilm(name, thickness, rms, corrLength, seed) is a film with a flat bottom "+
+		"at z = -thickness/2 and a top surface with Gaussian-correlated roughness around z = thickness/2. "+
+		"The height profile is written to the zarr group name")
+}
+
+func edgeRoughStrip(name string, width, rms, corrLength float64, seed int) shape {
+	checkRoughness(name, rms, corrLength)
+	m := GetMesh()
+	n, c := m.Size(), m.CellSize()
+	rng := rand.New(rand.NewSource(int64(seed)))
+	periodic := [2]bool{m.PBCx != 0, false}
+	upper := scaledNoise(correlatedNoise(n[X], 1, c[X], c[Y], corrLength, periodic, rng), rms)
+	lower := scaledNoise(correlatedNoise(n[X], 1, c[X], c[Y], corrLength, periodic, rng), rms)
+
+	saveRoughness(name, "EdgeRoughStrip", rms, corrLength, seed, map[string][]float64{
+		"x": cellCenters(X), "upper": shiftedProfile(upper, width/2), "lower": shiftedProfile(lower, -width/2),
+	}, nil)
+	return func(x, y, z float64) bool {
+		fx := cellIndex(x, X)
+		return y < width/2+interpolate(upper, fx, 0, n[X], 1, periodic) &&
+			y >= -width/2+interpolate(lower, fx, 0, n[X], 1, periodic)
+	}
+}
+
+func roughFilm(name string, thickness, rms, corrLength float64, seed int) shape {
+	checkRoughness(name, rms, corrLength)
+	m := GetMesh()
+	n, c := m.Size(), m.CellSize()
+	rng := rand.New(rand.NewSource(int64(seed)))
+	periodic := [2]bool{m.PBCx != 0, m.PBCy != 0}
+	height := scaledNoise(correlatedNoise(n[X], n[Y], c[X], c[Y], corrLength, periodic, rng), rms)
+
+	saveRoughness(name, "RoughFilm", rms, corrLength, seed, map[string][]float64{
+		"x": cellCenters(X), "y": cellCenters(Y), "top": shiftedProfile(height, thickness/2),
+	}, []int{n[Y], n[X]})
+	return func(x, y, z float64) bool {
+		top := thickness/2 + interpolate(height, cellIndex(x, X), cellIndex(y, Y), n[X], n[Y], periodic)
+		return z >= -thickness/2 && z < top
+	}
+}
+
+func checkRoughness(name string, rms, corrLength float64) {
+	if name == "" {
+		log.Log.ErrAndExit("Roughness profiles need a name")
+	}
+	if rms < 0 || corrLength < 0 {
+		log.Log.ErrAndExit("%s: the rms amplitude and the correlation length cannot be negative", name)
+	}
+}
+
+// correlatedNoise returns nx*ny values (x varying fastest) of white noise filtered by the kernel exp(-2r²/ξ²),
+// whose autocorrelation is proportional to exp(-r²/ξ²). Non-periodic directions are padded by the kernel
+// width so that the ends are not correlated with each other.
+func correlatedNoise(nx, ny int, dx, dy, corrLength float64, periodic [2]bool, rng *rand.Rand) []float64 {
+	size := [2]int{nx, ny}
+	step := [2]float64{dx, dy}
+	var kernel [2][]float64
+	var pad [2]int
+	for k := range size {
+		if size[k] == 1 {
+			kernel[k] = []float64{1}
+			continue
+		}
+		half := 0
+		kernel[k] = []float64{1}
+		if corrLength > 0 {
+			half = int(math.Ceil(2 * corrLength / step[k]))
+			kernel[k] = make([]float64, 2*half+1)
+			for i := range kernel[k] {
+				r := float64(i-half) * step[k]
+				kernel[k][i] = math.Exp(-2 * r * r / (corrLength * corrLength))
+			}
+		}
+		if !periodic[k] {
+			pad[k] = half
+		}
+	}
+
+	px, py := nx+2*pad[X], ny+2*pad[Y]
+	noise := make([]float64, px*py)
+	for i := range noise {
+		noise[i] = rng.NormFloat64()
+	}
+	noise = convolve(noise, px, py, kernel[X], 1, px, periodic[X])
+	noise = convolve(noise, py, px, kernel[Y], px, 1, periodic[Y])
+
+	out := make([]float64, 0, nx*ny)
+	for iy := pad[Y]; iy < pad[Y]+ny; iy++ {
+		out = append(out, noise[iy*px+pad[X]:iy*px+pad[X]+nx]...)
+	}
+	return out
+}
+
+// convolve filters the n values along one direction of f, which are stride apart, for each of the m lines
+// that are lineStride apart.
+func convolve(f []float64, n, m int, kernel []float64, stride, lineStride int, periodic bool) []float64 {
+	out := make([]float64, len(f))
+	half := len(kernel) / 2
+	for line := 0; line < m; line++ {
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			for k, w := range kernel {
+				j := i + k - half
+				if periodic {
+					j = ((j % n) + n) % n
+				} else if j < 0 || j >= n {
+					continue
+				}
+				sum += w * f[line*lineStride+j*stride]
+			}
+			out[line*lineStride+i*stride] = sum
+		}
+	}
+	return out
+}
+
+// scaledNoise shifts and scales f in place to a mean of 0 and the given rms amplitude.
+func scaledNoise(f []float64, rms float64) []float64 {
+	mean, sq := 0.0, 0.0
+	for _, v := range f {
+		mean += v
+	}
+	mean /= float64(len(f))
+	for _, v := range f {
+		sq += (v - mean) * (v - mean)
+	}
+	scale := 0.0
+	if sq > 0 {
+		scale = rms / math.Sqrt(sq/float64(len(f)))
+	}
+	for i := range f {
+		f[i] = (f[i] - mean) * scale
+	}
+	return f
+}
+
+// cellIndex returns the fractional cell index of the coordinate x along direction dir, 0 at the first cell center.
+func cellIndex(x float64, dir int) float64 {
+	m := GetMesh()
+	n, c := m.Size(), m.CellSize()
+	shift := 0.0
+	switch dir {
+	case X:
+		shift = totalShift
+	case Y:
+		shift = totalYShift
+	}
+	return (x+shift)/c[dir] + 0.5*float64(n[dir]-1)
+}
+
+// cellCenters returns the coordinates of the cell centers along direction dir.
+func cellCenters(dir int) []float64 {
+	out := make([]float64, GetMesh().Size()[dir])
+	for i := range out {
+		var idx [3]int
+		idx[dir] = i
+		out[i] = index2Coord(idx[X], idx[Y], idx[Z])[dir]
+	}
+	return out
+}
+
+// interpolate returns the bilinear interpolation of the nx*ny values f at the fractional indices (fx, fy),
+// periodic or clamped at the ends.
+func interpolate(f []float64, fx, fy float64, nx, ny int, periodic [2]bool) float64 {
+	index := func(fi float64, n int, periodic bool) (int, int, float64) {
+		i0 := int(math.Floor(fi))
+		t := fi - float64(i0)
+		if periodic {
+			return ((i0 % n) + n) % n, (((i0 + 1) % n) + n) % n, t
+		}
+		if fi <= 0 {
+			return 0, 0, 0
+		}
+		if fi >= float64(n-1) {
+			return n - 1, n - 1, 0
+		}
+		return i0, i0 + 1, t
+	}
+	x0, x1, tx := index(fx, nx, periodic[X])
+	y0, y1, ty := index(fy, ny, periodic[Y])
+	return (1-ty)*((1-tx)*f[y0*nx+x0]+tx*f[y0*nx+x1]) + ty*((1-tx)*f[y1*nx+x0]+tx*f[y1*nx+x1])
+}
+
+func shiftedProfile(f []float64, offset float64) []float64 {
+	out := make([]float64, len(f))
+	for i, v := range f {
+		out[i] = v + offset
+	}
+	return out
+}
+
+// saveRoughness writes the profiles to the zarr group name, and the parameters to its attributes and to the
+// metadata of the simulation. 2D profiles are flattened with x varying fastest, their shape is in the attributes.
+func saveRoughness(name, generator string, rms, corrLength float64, seed int, profiles map[string][]float64, dims []int) {
+	attrs := map[string]any{"generator": generator, "rms": rms, "correlation_length": corrLength, "seed": seed}
+	if dims != nil {
+		attrs["shape"] = dims
+	}
+	zarr.InitZgroup(name, OD())
+	zarr.SaveZattrs(OD()+name, attrs)
+	for key, values := range profiles {
+		zarr.SaveFloat64Array(OD()+name+"/"+key, values)
+	}
+	EngineState.Metadata.Add(name, fmt.Sprintf("%s(rms=%g, correlation_length=%g, seed=%d)", generator, rms, corrLength, seed))
+}
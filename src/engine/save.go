@@ -1,7 +1,10 @@
 package engine
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"math"
 	"path"
 	"reflect"
 	"strings"
@@ -10,6 +13,7 @@ import (
 	"github.com/MathieuMoalic/amumax/src/data"
 	"github.com/MathieuMoalic/amumax/src/draw"
 	"github.com/MathieuMoalic/amumax/src/fsutil"
+	"github.com/MathieuMoalic/amumax/src/hooks"
 	"github.com/MathieuMoalic/amumax/src/log"
 	"github.com/MathieuMoalic/amumax/src/oommf"
 )
@@ -25,6 +29,9 @@ func init() {
 var (
 	filenameFormat = "%s%06d"   // formatting string for auto filenames.
 	snapshotFormat = "jpg"      // user-settable snapshot format
+	snapshotCmap   = ""         // named colormap for scalar snapshots, empty for black-white
+	snapshotCbar   = false      // add a colorbar with min/max labels to scalar snapshots
+	snapshotLabels = false      // write the quantity name, unit and time on snapshots
 	outputFormat   = OVF2Binary // user-settable output format
 )
 
@@ -61,21 +68,26 @@ func saveAsOVF(q Quantity, fname string) {
 	defer cuda.Recycle(buffer)
 	info := oommf.Meta{Time: Time, Name: nameOf(q), Unit: unitOf(q), CellSize: MeshOf(q).CellSize()}
 	data := buffer.HostCopy() // must be copy (async io)
-	queOutput(func() { saveAsSync(fname, data, info, outputFormat) })
+	hp := hookPayload(hooks.Save, nameOf(q), fname)
+	queOutput(func() {
+		saveAsSync(fname, data, info, outputFormat)
+		Hooks.Fire(hp)
+	})
 }
 
 // Save image once, with auto file name
 func snapshot(q Quantity) {
 	qname := nameOf(q)
-	fname := fmt.Sprintf(OD()+filenameFormat+"."+snapshotFormat, qname, autonum[qname])
-	s := ValueOf(q)
-	defer cuda.Recycle(s)
-	data := s.HostCopy() // must be copy (asyncio)
-	queOutput(func() { snapshotSync(fname, data) })
+	queSnapshot(q, fmt.Sprintf(OD()+filenameFormat+"."+snapshotFormat, qname, autonum[qname]), nil)
 	autonum[qname]++
 }
 
 func snapshotAs(q Quantity, fname string) {
+	queSnapshot(q, snapshotPath(fname), nil)
+}
+
+// snapshotPath prefixes fname with the output directory and adds the default extension if it has none.
+func snapshotPath(fname string) string {
 	if !strings.HasPrefix(fname, OD()) {
 		fname = OD() + fname // don't clean, turns http:// in http:/
 	}
@@ -83,26 +95,164 @@ func snapshotAs(q Quantity, fname string) {
 	if path.Ext(fname) == "" {
 		fname += ("." + StringFromOutputFormat[outputFormat])
 	}
+	return fname
+}
+
+// Save an image of the cross-section of q in plane xy, xz or yz at a cell index, with auto file name
+func snapshotPlane(q Quantity, plane string, index int) {
+	name := fmt.Sprintf("%s_%s%d", nameOf(q), plane, index)
+	snapshotPlaneAs(q, plane, index, fmt.Sprintf(OD()+filenameFormat+"."+snapshotFormat, name, autonum[name]))
+	autonum[name]++
+}
+
+func snapshotPlaneAs(q Quantity, plane string, index int, fname string) {
+	cellsize := MeshOf(q).CellSize()
+	queSnapshot(q, snapshotPath(fname), func(s *data.Slice) (*data.Slice, float64, error) {
+		return draw.Plane(s, cellsize, plane, index)
+	})
+}
+
+// Save an image of q sampled on the nu x nv pixels of the parallelogram origin + a*u + b*v, a and b in [0, 1]
+func snapshotSection(q Quantity, origin, u, v data.Vector, nu, nv int, fname string) {
+	m := MeshOf(q)
+	cellsize, size := m.CellSize(), sizeOf(q)
+	// from centered coordinates to the corner of the first cell
+	corner := data.Vector{
+		origin[X] + totalShift + 0.5*cellsize[X]*float64(size[X]),
+		origin[Y] + totalYShift + 0.5*cellsize[Y]*float64(size[Y]),
+		origin[Z] + 0.5*cellsize[Z]*float64(size[Z]),
+	}
+	queSnapshot(q, snapshotPath(fname), func(s *data.Slice) (*data.Slice, float64, error) {
+		return draw.Section(s, cellsize, corner, u, v, nu, nv)
+	})
+}
+
+// queSnapshot renders q to fname in the output queue, after extracting a plane with cut if not nil.
+func queSnapshot(q Quantity, fname string, cut func(*data.Slice) (*data.Slice, float64, error)) {
+	fname, format := snapshotFile(fname)
 	s := ValueOf(q)
 	defer cuda.Recycle(s)
 	data := s.HostCopy() // must be copy (asyncio)
-	queOutput(func() { snapshotSync(fname, data) })
+	opt, meta := snapshotOptions(q), newSnapshotMeta(q, format)
+	hp := hookPayload(hooks.Save, nameOf(q), fname)
+	queOutput(func() {
+		if cut != nil {
+			var err error
+			data, opt.Aspect, err = cut(data)
+			if err != nil {
+				log.Log.Warn("Error while rendering snapshot %s: %v", fname, err)
+				return
+			}
+			if meta != nil {
+				meta.Size = data.Size()
+			}
+		}
+		snapshotSync(fname, format, data, opt, meta)
+		Hooks.Fire(hp)
+	})
+}
+
+// snapshotFile returns the file name and the draw format of a snapshot.
+// 16-bit pngs, asked for with SnapshotFormat = "png16" or a .png16 file name, keep the .png extension.
+func snapshotFile(fname string) (string, string) {
+	ext := strings.ToLower(path.Ext(fname))
+	switch {
+	case ext == ".png16":
+		return strings.TrimSuffix(fname, path.Ext(fname)) + ".png", ext
+	case ext == ".png" && snapshotFormat == "png16":
+		return fname, ".png16"
+	}
+	return fname, ext
+}
+
+// snapshotMeta is the JSON sidecar of the lossless snapshot formats, needed to reload their values.
+type snapshotMeta struct {
+	Quantity string     `json:"quantity"`
+	Unit     string     `json:"unit"`
+	Format   string     `json:"format"`
+	Min      float64    `json:"min"`
+	Max      float64    `json:"max"`
+	CellSize [3]float64 `json:"cellsize"`
+	Size     [3]int     `json:"size"`
+	NComp    int        `json:"ncomp"`
+	Time     float64    `json:"time"`
+	Values   string     `json:"values"`
+}
+
+// newSnapshotMeta returns the sidecar of a snapshot of q taken now, nil for the lossy formats.
+func newSnapshotMeta(q Quantity, format string) *snapshotMeta {
+	m := &snapshotMeta{
+		Quantity: nameOf(q),
+		Unit:     unitOf(q),
+		Format:   strings.TrimPrefix(format, "."),
+		CellSize: MeshOf(q).CellSize(),
+		Size:     sizeOf(q),
+		NComp:    q.NComp(),
+		Time:     Time,
+	}
+	switch format {
+	case ".png16":
+		m.Values = "averaged over z, value = min + (max - min) * pixel / 65535, first row is the top (largest y)"
+	case ".tif", ".tiff":
+		m.Values = "raw float32, one page per z layer and one sample per component, first row is the top (largest y)"
+	default:
+		return nil
+	}
+	return m
+}
+
+// snapshotOptions returns the rendering settings of a snapshot of q taken now.
+func snapshotOptions(q Quantity) draw.Options {
+	c := MeshOf(q).CellSize()
+	opt := draw.Options{Min: "auto", Max: "auto", ArrowSize: 16, Colorbar: snapshotCbar, Aspect: c[Y] / c[X]}
+	if snapshotCmap != "" {
+		cmap, err := draw.ColorMap(snapshotCmap)
+		if err != nil {
+			log.Log.Warn("Snapshot: %v, using the default colors", err)
+		} else {
+			opt.ColorMap = &draw.ColorMapSpec{Cmap: cmap, Ccomp: -1}
+		}
+	}
+	if snapshotLabels {
+		opt.Title = TitleWithUnit(nameOf(q), q)
+		opt.Time, opt.ShowTime = Time, true
+	}
+	return opt
 }
 
 // synchronous snapshot
-func snapshotSync(fname string, output *data.Slice) {
-	f, err := fsutil.Create(fname)
+func snapshotSync(fname, format string, output *data.Slice, opt draw.Options, meta *snapshotMeta) {
+	// rendered in memory first, so that a snapshot that cannot be rendered leaves no empty file
+	var buf bytes.Buffer
+	if err := draw.Render(&buf, output, format, opt); err != nil {
+		log.Log.Warn("Error while rendering snapshot: %v", err)
+		return
+	}
+	log.Log.PanicIfError(fsutil.Put(fname, buf.Bytes()))
+	if meta == nil {
+		return
+	}
+	if format == ".png16" {
+		min, max := draw.Scale(output, opt.Min, opt.Max)
+		meta.Min, meta.Max = float64(min), float64(max)
+	} else {
+		meta.Min, meta.Max = hostExtrema(output)
+	}
+	u, err := json.MarshalIndent(meta, "", "\t")
 	log.Log.PanicIfError(err)
-	defer func() {
-		if cerr := f.Close(); cerr != nil {
-			log.Log.Warn("Error while closing file: %v", cerr)
+	err = fsutil.Put(strings.TrimSuffix(fname, path.Ext(fname))+".json", u)
+	log.Log.PanicIfError(err)
+}
+
+// hostExtrema returns the extrema of all the components of s.
+func hostExtrema(s *data.Slice) (min, max float64) {
+	min, max = math.Inf(1), math.Inf(-1)
+	for _, comp := range s.Host() {
+		for _, v := range comp {
+			min, max = math.Min(min, float64(v)), math.Max(max, float64(v))
 		}
-	}()
-	arrowSize := 16
-	err = draw.RenderFormat(f, output, "auto", "auto", arrowSize, path.Ext(fname))
-	if err != nil {
-		log.Log.Warn("Error while rendering snapshot: %v", err)
 	}
+	return min, max
 }
 
 // synchronous save
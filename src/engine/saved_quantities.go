@@ -13,6 +13,7 @@ import (
 	"github.com/MathieuMoalic/amumax/src/cuda"
 	"github.com/MathieuMoalic/amumax/src/data"
 	"github.com/MathieuMoalic/amumax/src/fsutil"
+	"github.com/MathieuMoalic/amumax/src/hooks"
 	"github.com/MathieuMoalic/amumax/src/log"
 	"github.com/MathieuMoalic/amumax/src/zarr"
 )
@@ -53,9 +54,11 @@ func (sq *savedQuantity) Save() {
 	defer cuda.Recycle(buffer)
 	dataSlice := buffer.HostCopy()
 	tstep := len(sq.times) - 1
+	hp := hookPayload(hooks.Save, sq.name, OD()+sq.name)
 	queOutput(func() {
 		err := syncSave(dataSlice, sq.name, tstep, sq.chunks)
 		log.Log.PanicIfError(err)
+		Hooks.Fire(hp)
 	})
 }
 
@@ -104,6 +107,14 @@ func (sqs *savedQuantitiesType) createSavedQuantity(q Quantity, name string, rch
 	return newZArray
 }
 
+// restart recreates the datasets in the current output directory, without any saved time step.
+func (sqs *savedQuantitiesType) restart(quantities []savedQuantity) {
+	sqs.Quantities = nil
+	for _, sq := range quantities {
+		sqs.createSavedQuantity(sq.q, sq.name, sq.rchunks, sq.period)
+	}
+}
+
 func (sqs *savedQuantitiesType) updateSavedQuantity(q Quantity, name string, rchunks requestedChunking, period float64) {
 	sq := sqs.getSavedQuantity(name)
 	if sq.rchunks != rchunks {
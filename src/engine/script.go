@@ -8,6 +8,7 @@ import (
 
 	"github.com/MathieuMoalic/amumax/src/cuda"
 	"github.com/MathieuMoalic/amumax/src/fsutil"
+	"github.com/MathieuMoalic/amumax/src/hooks"
 	"github.com/MathieuMoalic/amumax/src/log"
 	"github.com/MathieuMoalic/amumax/src/mesh"
 	"github.com/MathieuMoalic/amumax/src/script"
@@ -86,6 +87,7 @@ func EvalFile(code *script.BlockStmt) {
 				Regions.Alloc()
 				EngineState.Metadata.Init(OD(), StartTime, cuda.GPUInfoOld)
 				EngineState.Metadata.AddMesh(&Mesh)
+				Hooks.Fire(hookPayload(hooks.Start, ""))
 			}
 		}
 	}
@@ -79,6 +79,10 @@ func init() {
 	DeclFunc("SaveOvfAs", saveAsOVF, "Save space-dependent quantity with custom filename")
 	DeclFunc("Snapshot", snapshot, "Save image of quantity")
 	DeclFunc("SnapshotAs", snapshotAs, "Save image of quantity with custom filename")
+	DeclFunc("SnapshotPlane", snapshotPlane, "Save image of the cross-section of quantity in plane \"xy\", \"xz\" or \"yz\" at a cell index")
+	DeclFunc("SnapshotPlaneAs", snapshotPlaneAs, "Save image of the cross-section of quantity in plane \"xy\", \"xz\" or \"yz\" at a cell index, with custom filename")
+	DeclFunc("SnapshotSection", snapshotSection, "SnapshotSection(q, origin, u, v, nu, nv, filename) saves an image of quantity interpolated on the "+
+		"nu x nv pixels of the plane origin + a*u + b*v, a and b in [0, 1], positions in m")
 
 	DeclFunc("Ellipsoid", ellipsoid, "3D Ellipsoid with axes in meter")
 	DeclFunc("Ellipse", ellipse, "2D Ellipse with axes in meter")
@@ -102,6 +106,10 @@ func init() {
 	DeclFunc("Universe", universe, "Entire space")
 	DeclFunc("Cell", cell, "Single cell with given integer index (i, j, k)")
 	DeclFunc("ImageShape", imageShape, "Use black/white image as shape")
+	DeclFunc("STLShape", stlShape, "Inside of the closed triangle mesh in an STL file, with the "+
+		"coordinates multiplied by scale (second argument) to convert them to meter")
+	DeclFunc("OBJShape", objShape, "Inside of the closed triangle mesh in a Wavefront OBJ file, with the "+
+		"coordinates multiplied by scale (second argument) to convert them to meter")
 	DeclFunc("GrainRoughness", grainRoughness, "Grainy surface with different heights per grain "+
 		"with a typical grain size (first argument), minimal height (second argument), and maximal "+
 		"height (third argument). The last argument is a seed for the random number generator.")
@@ -55,7 +55,10 @@ func init() {
 	declVar("MinimizeMaxSteps", &minimizeMaxSteps, "")
 	declVar("MinimizeMaxTimeSeconds", &minimizeMaxTimeSeconds, "")
 	declVar("RelaxTorqueThreshold", &relaxTorqueThreshold, "MaxTorque threshold for relax(). If set to -1 (default), relax() will stop when the average torque is steady or increasing.")
-	declVar("SnapshotFormat", &snapshotFormat, "Image format for snapshots: jpg, png or gif.")
+	declVar("SnapshotFormat", &snapshotFormat, "Image format for snapshots: jpg, png, gif, png16 (16-bit grayscale) or tiff (raw float32 values), the last two with a JSON sidecar.")
+	declVar("SnapshotColormap", &snapshotCmap, "Colormap for scalar snapshots: gray, viridis, magma, RdBu or twilight, with _r to reverse it. Empty for black-white.")
+	declVar("SnapshotColorbar", &snapshotCbar, "Whether scalar snapshots get a colorbar with min/max labels")
+	declVar("SnapshotLabels", &snapshotLabels, "Whether snapshots show the quantity name, unit and time")
 
 	declVar("ShiftMagL", &shiftMagL, "Upon shift, insert this magnetization from the left")
 	declVar("ShiftMagR", &shiftMagR, "Upon shift, insert this magnetization from the right")
@@ -4,10 +4,12 @@ import (
 	"image"
 	_ "image/jpeg" // register JPEG format for image decoding
 	_ "image/png"  // register PNG format for image decoding
+	"io"
 	"math"
 
 	"github.com/MathieuMoalic/amumax/src/fsutil"
 	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/trimesh"
 )
 
 // geometrical shape for setting sample geometry
@@ -230,6 +232,39 @@ func imageShape(fname string) shape {
 	}
 }
 
+// stlShape is the inside of a closed triangle mesh from an STL file, with coordinates multiplied by scale.
+func stlShape(fname string, scale float64) shape {
+	return meshShape(fname, scale, trimesh.ReadSTL)
+}
+
+// objShape is the inside of a closed triangle mesh from a Wavefront OBJ file.
+func objShape(fname string, scale float64) shape {
+	return meshShape(fname, scale, trimesh.ReadOBJ)
+}
+
+func meshShape(fname string, scale float64, read func(io.Reader) (*trimesh.Mesh, error)) shape {
+	r, err := fsutil.Open(fname)
+	log.Log.PanicIfError(err)
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Log.PanicIfError(err)
+		}
+	}()
+	m, err := read(r)
+	if err != nil {
+		log.Log.ErrAndExit("Failed to read %s: %v", fname, err)
+	}
+	if len(m.Triangles) == 0 {
+		log.Log.ErrAndExit("%s contains no triangles", fname)
+	}
+	m.Scale(scale)
+	log.Log.Info("Mesh shape %s: %v", fname, m)
+	if n := m.OpenEdges(); n != 0 {
+		log.Log.Warn("The mesh in %s is not closed (%d open edges), the inside test may be wrong", fname, n)
+	}
+	return m.Inside
+}
+
 func grainRoughness(grainsize, zmin, zmax float64, seed int) shape {
 	t := newTesselation(grainsize, 0, 256, int64(seed))
 	return func(x, y, z float64) bool {
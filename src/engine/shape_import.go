@@ -0,0 +1,143 @@
+package engine
+
+// Shapes from volumetric data: stacks of images and scalar arrays loaded from zarr or ovf files.
+
+import (
+	"image"
+	"math"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/fsutil"
+	"github.com/MathieuMoalic/amumax/src/log"
+)
+
+func init() {
+	DeclFunc("LoadImageStack", loadImageStack, "Loads the images matching a pattern like \"stack/*.png\", one per "+
+		"z-layer in the order of their names, as a scalar array of darkness (0: white or transparent, 1: black)")
+	DeclFunc("ImageStackShape", imageStackShape, "Use a stack of black/white images, one per z-layer, as shape")
+	DeclFunc("ArrayShape", arrayShape, "Cells where a scalar array (first argument), resampled onto the mesh, "+
+		"is larger than threshold (second argument)")
+	DeclFunc("ArrayFillShape", arrayFillShape, "Uses a scalar array, resampled onto the mesh and clamped to 0..1, "+
+		"as fill fraction of the cells with EdgeSmooth. Without EdgeSmooth cells are inside above 0.5")
+}
+
+func loadImageStack(pattern string) *data.Slice {
+	files, err := fsutil.Glob(pattern)
+	log.Log.PanicIfError(err)
+	if len(files) == 0 {
+		log.Log.ErrAndExit("LoadImageStack: no file matches %s", pattern)
+	}
+	var stack *data.Slice
+	for iz, fname := range files {
+		img := decodeImage(fname)
+		width, height := img.Bounds().Dx(), img.Bounds().Dy()
+		if stack == nil {
+			stack = data.NewSlice(1, [3]int{width, height, len(files)})
+		}
+		if size := stack.Size(); size[X] != width || size[Y] != height {
+			log.Log.ErrAndExit("LoadImageStack: %s is %dx%d, the previous images are %dx%d", fname, width, height, size[X], size[Y])
+		}
+		layer := stack.Scalars()[iz]
+		origin := img.Bounds().Min
+		for iy := 0; iy < height; iy++ {
+			for ix := 0; ix < width; ix++ {
+				// the first row of the image is the top, at the largest y
+				r, g, b, a := img.At(origin.X+ix, origin.Y+height-1-iy).RGBA()
+				brightness := float64(r+g+b) / (3 * 0xFFFF)
+				layer[iy][ix] = float32((1 - brightness) * float64(a) / 0xFFFF)
+			}
+		}
+	}
+	log.Log.Info("Loaded %d images of %dx%d pixels from %s", len(files), stack.Size()[X], stack.Size()[Y], pattern)
+	return stack
+}
+
+func decodeImage(fname string) image.Image {
+	r, err := fsutil.Open(fname)
+	log.Log.PanicIfError(err)
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Log.PanicIfError(err)
+		}
+	}()
+	img, _, err := image.Decode(r)
+	log.Log.PanicIfError(err)
+	return img
+}
+
+func imageStackShape(pattern string) shape {
+	return arrayShape(loadImageStack(pattern), 0.5)
+}
+
+func arrayShape(s *data.Slice, threshold float64) shape {
+	values := meshValues(s, "ArrayShape")
+	return func(x, y, z float64) bool {
+		i, _, ok := meshCell(x, y, z)
+		return ok && float64(values[i]) > threshold
+	}
+}
+
+// arrayFillShape is inside on a fraction f of the points at which EdgeSmooth samples a cell with the value f.
+// Each sampling sub-cell gets a different level in 0..1, and is inside when its level is below f. The centre
+// of the cell, which is all DefRegion and SetGeom without EdgeSmooth look at, is inside above 0.5.
+func arrayFillShape(s *data.Slice) shape {
+	values := meshValues(s, "ArrayFillShape")
+	return func(x, y, z float64) bool {
+		i, u, ok := meshCell(x, y, z)
+		if !ok {
+			return false
+		}
+		const eps = 1e-9
+		if math.Abs(u[X]-0.5) < eps && math.Abs(u[Y]-0.5) < eps && math.Abs(u[Z]-0.5) < eps {
+			return values[i] > 0.5
+		}
+		N := max(edgeSmooth, 1)
+		sub := func(u float64) int { return min(max(int(u*float64(N)), 0), N-1) }
+		level := (float64((sub(u[X])*N+sub(u[Y]))*N+sub(u[Z])) + 0.5) / float64(N*N*N)
+		return float64(values[i]) > level
+	}
+}
+
+// meshValues returns the values of a scalar array resampled onto the mesh.
+func meshValues(s *data.Slice, caller string) []float32 {
+	if s.NComp() != 1 {
+		log.Log.ErrAndExit("%s needs a scalar array, this one has %d components", caller, s.NComp())
+	}
+	if s.Size() != GetMesh().Size() {
+		log.Log.Info("%s: resampling the array from %v to %v cells", caller, s.Size(), GetMesh().Size())
+	}
+	return data.Resample(s, GetMesh().Size()).Host()[0]
+}
+
+// vertexCell is the cell whose vertices setGeom is checking, nil otherwise. The vertices are shared with the
+// neighbouring cells, meshCell attributes them to this one.
+var vertexCell *[3]int
+
+// meshCell returns the index of the cell containing (x, y, z), the position inside this cell (0..1)
+// and whether the point is inside the mesh. It is the inverse of index2Coord.
+func meshCell(x, y, z float64) (int, data.Vector, bool) {
+	m := GetMesh()
+	n := m.Size()
+	c := m.CellSize()
+	f := data.Vector{
+		(x+totalShift)/c[X] + 0.5*float64(n[X]),
+		(y+totalYShift)/c[Y] + 0.5*float64(n[Y]),
+		z/c[Z] + 0.5*float64(n[Z]),
+	}
+	var idx [3]int
+	var u data.Vector
+	for k := range f {
+		idx[k] = int(math.Floor(f[k]))
+		if vertexCell != nil {
+			// on a face of the cell, up to rounding
+			if face := math.Round(f[k]); math.Abs(f[k]-face) < 1e-6 && (int(face) == vertexCell[k] || int(face) == vertexCell[k]+1) {
+				idx[k] = vertexCell[k]
+			}
+		}
+		if idx[k] < 0 || idx[k] >= n[k] {
+			return 0, u, false
+		}
+		u[k] = f[k] - float64(idx[k])
+	}
+	return data.Index(n, idx[X], idx[Y], idx[Z]), u, true
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/mesh"
+)
+
+// withMesh sets the mesh for a test and restores it afterwards.
+func withMesh(t *testing.T, nx, ny, nz int, c float64) {
+	saved := Mesh
+	Mesh = *mesh.NewMesh(nx, ny, nz, c, c, c, 0, 0, 0)
+	Mesh.Create()
+	t.Cleanup(func() { Mesh = saved })
+}
+
+func TestMeshCell(t *testing.T) {
+	const nx, ny, nz, c = 4, 3, 2, 2e-9
+	withMesh(t, nx, ny, nz, c)
+	n := [3]int{nx, ny, nz}
+	for iz := 0; iz < nz; iz++ {
+		for iy := 0; iy < ny; iy++ {
+			for ix := 0; ix < nx; ix++ {
+				r := index2Coord(ix, iy, iz)
+				i, u, ok := meshCell(r[X], r[Y], r[Z])
+				if !ok || i != data.Index(n, ix, iy, iz) {
+					t.Errorf("centre of (%d, %d, %d): cell %d, %v", ix, iy, iz, i, ok)
+				}
+				for k := range u {
+					if math.Abs(u[k]-0.5) > 1e-9 {
+						t.Errorf("centre of (%d, %d, %d) at %v in the cell", ix, iy, iz, u)
+					}
+				}
+
+				// the vertices belong to the cell whose vertices setGeom checks, even at the edges of the mesh
+				vertexCell = &[3]int{ix, iy, iz}
+				for _, d := range [][3]float64{{-1, -1, -1}, {1, 1, 1}, {-1, 1, -1}, {1, -1, 1}} {
+					i, _, ok := meshCell(r[X]+d[X]*c/2, r[Y]+d[Y]*c/2, r[Z]+d[Z]*c/2)
+					if !ok || i != data.Index(n, ix, iy, iz) {
+						t.Errorf("vertex %v of (%d, %d, %d): cell %d, %v", d, ix, iy, iz, i, ok)
+					}
+				}
+				vertexCell = nil
+			}
+		}
+	}
+	if _, _, ok := meshCell(nx*c, 0, 0); ok {
+		t.Error("a point outside the mesh is in a cell")
+	}
+}
+
+func TestArrayFillShape(t *testing.T) {
+	const c = 1e-9
+	withMesh(t, 4, 1, 1, c)
+	saved, savedShape := edgeSmooth, Geometry.shape
+	t.Cleanup(func() { edgeSmooth, Geometry.shape = saved, savedShape })
+
+	a := data.NewSlice(1, [3]int{4, 1, 1})
+	fill := []float32{0, 0.3, 0.7, 1}
+	copy(a.Host()[0], fill)
+	s := arrayFillShape(a)
+	for _, N := range []int{0, 2, 3, 4} {
+		edgeSmooth = N
+		Geometry.shape = s
+		for ix, f := range fill {
+			// the centre, which is what DefRegion looks at
+			r := index2Coord(ix, 0, 0)
+			if got, want := s(r[X], r[Y], r[Z]), f > 0.5; got != want {
+				t.Errorf("EdgeSmooth = %d: centre of a cell filled at %g is inside: %v, want %v", N, f, got, want)
+			}
+			if N == 0 {
+				continue
+			}
+			// the fraction of the samples of EdgeSmooth
+			want := math.Round(float64(f)*float64(N*N*N)) / float64(N*N*N)
+			if got := Geometry.cellVolume(ix, 0, 0); math.Abs(float64(got)-want) > 1.5/float64(N*N*N) {
+				t.Errorf("EdgeSmooth = %d: cell filled at %g has the volume %g, want about %g", N, f, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package engine
+
+// Periodic arrays of shapes (antidot lattices, magnonic crystals, arrays of dots), mirrors and polygons.
+
+import (
+	"math"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/log"
+)
+
+func init() {
+	DeclFunc("Lattice", newLattice, "Lattice(a1, a2, a3, n1, n2, n3) is the lattice of n1 x n2 x n3 points spanned "+
+		"by the vectors a1, a2, a3 (m), centered on the origin. Use it with shape.Array(lattice)")
+	DeclFunc("SquareLattice", squareLattice, "SquareLattice(a, nx, ny) is the square lattice of nx x ny points with "+
+		"period a (m) in the xy plane, centered on the origin. ny = 1 gives a 1D array")
+	DeclFunc("HexLattice", hexLattice, "HexLattice(a, nx, ny) is the hexagonal lattice of ny rows of nx points with "+
+		"period a (m) in the xy plane, every other row shifted by a/2, centered on the origin")
+	DeclFunc("Polygon", polygon, "Polygon(vertices...) is the polygon in the xy plane with the given vertices, "+
+		"e.g. Polygon(vector(0, 0, 0), vector(100e-9, 0, 0), vector(0, 50e-9, 0))")
+	DeclFunc("DefRegionArray", defRegionArray, "DefRegionArray(startRegion, shape, lattice) puts every element of "+
+		"shape.Array(lattice) in its own region, startRegion + the element index")
+}
+
+// lattice is a finite set of points: Bravais lattice points (i, j, k) plus each basis offset. Elements are
+// numbered in the order of the lattice points, then of the basis offsets.
+type lattice struct {
+	a         [3]data.Vector
+	inv       [3]data.Vector // rows of the inverse of the matrix with the columns a
+	basis     []data.Vector
+	index     map[[4]int]int // (i, j, k, basis offset) to element
+	positions []data.Vector  // by element
+}
+
+func newLattice(a1, a2, a3 data.Vector, n1, n2, n3 int) *lattice {
+	l := bravaisLattice([3]data.Vector{a1, a2, a3})
+	if n1 < 1 || n2 < 1 || n3 < 1 {
+		log.Log.ErrAndExit("Lattice: the number of points should be at least 1, have %d x %d x %d", n1, n2, n3)
+	}
+	for k := 0; k < n3; k++ {
+		for j := 0; j < n2; j++ {
+			for i := 0; i < n1; i++ {
+				l.add([3]int{i, j, k})
+			}
+		}
+	}
+	l.center()
+	return l
+}
+
+func squareLattice(a float64, nx, ny int) *lattice {
+	return newLattice(data.Vector{a, 0, 0}, data.Vector{0, a, 0}, data.Vector{0, 0, a}, nx, ny, 1)
+}
+
+func hexLattice(a float64, nx, ny int) *lattice {
+	if nx < 1 || ny < 1 {
+		log.Log.ErrAndExit("HexLattice: the number of points should be at least 1, have %d x %d", nx, ny)
+	}
+	// rectangular lattice of two rows, the second one shifted by a/2
+	h := a * math.Sqrt(3) / 2
+	l := bravaisLattice([3]data.Vector{{a, 0, 0}, {0, 2 * h, 0}, {0, 0, a}})
+	l.basis = []data.Vector{{0, 0, 0}, {a / 2, h, 0}}
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			l.index[[4]int{i, j / 2, 0, j % 2}] = len(l.positions)
+			l.positions = append(l.positions, data.Vector{float64(i)*a + float64(j%2)*a/2, float64(j) * h, 0})
+		}
+	}
+	l.center()
+	return l
+}
+
+func bravaisLattice(a [3]data.Vector) *lattice {
+	det := a[0].Dot(a[1].Cross(a[2]))
+	if det == 0 {
+		log.Log.ErrAndExit("Lattice: the lattice vectors %v, %v and %v are not independent", a[0], a[1], a[2])
+	}
+	return &lattice{
+		a:     a,
+		inv:   [3]data.Vector{a[1].Cross(a[2]).Div(det), a[2].Cross(a[0]).Div(det), a[0].Cross(a[1]).Div(det)},
+		basis: []data.Vector{{0, 0, 0}},
+		index: make(map[[4]int]int),
+	}
+}
+
+func (l *lattice) add(ijk [3]int) {
+	for b, offset := range l.basis {
+		l.index[[4]int{ijk[0], ijk[1], ijk[2], b}] = len(l.positions)
+		p := l.a[0].Mul(float64(ijk[0])).Add(l.a[1].Mul(float64(ijk[1]))).Add(l.a[2].Mul(float64(ijk[2])))
+		l.positions = append(l.positions, p.Add(offset))
+	}
+}
+
+// center moves the lattice so that the center of its bounding box is the origin.
+func (l *lattice) center() {
+	lo := data.Vector{math.Inf(1), math.Inf(1), math.Inf(1)}
+	hi := data.Vector{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range l.positions {
+		for c := range p {
+			lo[c], hi[c] = math.Min(lo[c], p[c]), math.Max(hi[c], p[c])
+		}
+	}
+	shift := lo.Add(hi).Div(-2)
+	for i := range l.positions {
+		l.positions[i] = l.positions[i].Add(shift)
+	}
+	for b := range l.basis {
+		l.basis[b] = l.basis[b].Add(shift)
+	}
+}
+
+// Basis returns the lattice with every element replaced by copies at the given offsets (m).
+func (l *lattice) Basis(offsets ...data.Vector) *lattice {
+	if len(offsets) == 0 {
+		log.Log.ErrAndExit("Basis: no offsets given")
+	}
+	n := &lattice{a: l.a, inv: l.inv, index: make(map[[4]int]int)}
+	for _, b := range l.basis {
+		for _, o := range offsets {
+			n.basis = append(n.basis, b.Add(o))
+		}
+	}
+	// keep the element order of l, each element followed by its copies
+	keys := make([][4]int, len(l.positions))
+	for key, e := range l.index {
+		keys[e] = key
+	}
+	for e, key := range keys {
+		for o, offset := range offsets {
+			n.index[[4]int{key[0], key[1], key[2], key[3]*len(offsets) + o}] = len(n.positions)
+			n.positions = append(n.positions, l.positions[e].Add(offset))
+		}
+	}
+	return n
+}
+
+// Len returns the number of elements.
+func (l *lattice) Len() int { return len(l.positions) }
+
+// element returns the index of the element whose copy of s contains (x, y, z), or -1. Only the elements in
+// the unit cells around the point are checked, so each copy of s must fit in its neighbouring unit cells.
+func (l *lattice) element(s shape, x, y, z float64) int {
+	p := data.Vector{x, y, z}
+	for b, offset := range l.basis {
+		r := p.Sub(offset)
+		f := [3]int{int(math.Round(l.inv[0].Dot(r))), int(math.Round(l.inv[1].Dot(r))), int(math.Round(l.inv[2].Dot(r)))}
+		for dk := -1; dk <= 1; dk++ {
+			for dj := -1; dj <= 1; dj++ {
+				for di := -1; di <= 1; di++ {
+					e, ok := l.index[[4]int{f[0] + di, f[1] + dj, f[2] + dk, b}]
+					if !ok {
+						continue
+					}
+					q := p.Sub(l.positions[e])
+					if s(q[X], q[Y], q[Z]) {
+						return e
+					}
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// Array returns copies of the shape centered on every element of the lattice.
+func (s shape) Array(l *lattice) shape {
+	return func(x, y, z float64) bool {
+		return l.element(s, x, y, z) >= 0
+	}
+}
+
+func defRegionArray(startRegion int, s shape, l *lattice) {
+	defRegionID(startRegion)
+	if last := startRegion + l.Len() - 1; last >= NREGION {
+		log.Log.ErrAndExit("DefRegionArray: the %d elements need regions %d to %d, regions are 0-%d", l.Len(), startRegion, last, NREGION-1)
+	}
+	Regions.render(func(x, y, z float64) int {
+		if e := l.element(s, x, y, z); e >= 0 {
+			return startRegion + e
+		}
+		return -1
+	})
+	for e := range l.positions {
+		Regions.AddIndex(startRegion + e)
+	}
+}
+
+// MirrorX returns the shape reflected in the plane x = 0.
+func (s shape) MirrorX() shape { return s.Mirror(1, 0, 0) }
+
+// MirrorY returns the shape reflected in the plane y = 0.
+func (s shape) MirrorY() shape { return s.Mirror(0, 1, 0) }
+
+// MirrorZ returns the shape reflected in the plane z = 0.
+func (s shape) MirrorZ() shape { return s.Mirror(0, 0, 1) }
+
+// Mirror returns the shape reflected in the plane through the origin with normal (nx, ny, nz).
+func (s shape) Mirror(nx, ny, nz float64) shape {
+	n := data.Vector{nx, ny, nz}
+	if n.Len() == 0 {
+		log.Log.ErrAndExit("Mirror: the normal cannot be zero")
+	}
+	n = n.Div(n.Len())
+	return func(x, y, z float64) bool {
+		r := data.Vector{x, y, z}
+		r = r.Sub(n.Mul(2 * r.Dot(n)))
+		return s(r[X], r[Y], r[Z])
+	}
+}
+
+// polygon with the given vertices in the xy plane, using the even-odd rule for self-intersecting polygons.
+func polygon(vertices ...data.Vector) shape {
+	if len(vertices) < 3 {
+		log.Log.ErrAndExit("Polygon needs at least 3 vertices, have %d", len(vertices))
+	}
+	return func(x, y, z float64) bool {
+		inside := false
+		for i := range vertices {
+			a, b := vertices[i], vertices[(i+1)%len(vertices)]
+			if (a[Y] > y) != (b[Y] > y) && x < a[X]+(y-a[Y])*(b[X]-a[X])/(b[Y]-a[Y]) {
+				inside = !inside
+			}
+		}
+		return inside
+	}
+}
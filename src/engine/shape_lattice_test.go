@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+)
+
+func TestLatticeElement(t *testing.T) {
+	const a = 100e-9
+	dot := circle(20e-9)
+	tests := []struct {
+		name string
+		l    *lattice
+		n    int
+	}{
+		{"square", squareLattice(a, 3, 2), 6},
+		{"hexagonal", hexLattice(a, 3, 3), 9},
+		{"square with a basis", squareLattice(a, 2, 2).Basis(data.Vector{-25e-9, 0, 0}, data.Vector{25e-9, 0, 0}), 8},
+		// a basis on top of the two rows of the hexagonal lattice, whose offsets are numbered per row
+		{"hexagonal with a basis", hexLattice(a, 2, 3).Basis(data.Vector{0, -25e-9, 0}, data.Vector{0, 25e-9, 0}), 12},
+	}
+	for _, tt := range tests {
+		if tt.l.Len() != tt.n {
+			t.Errorf("%s: %d elements, want %d", tt.name, tt.l.Len(), tt.n)
+		}
+		for e, p := range tt.l.positions {
+			if got := tt.l.element(dot, p[X], p[Y], p[Z]); got != e {
+				t.Errorf("%s: element %d at %v, found %d", tt.name, e, p, got)
+			}
+			// off the dot, but still next to it
+			if got := tt.l.element(dot, p[X]+15e-9, p[Y], p[Z]); got != -1 {
+				t.Errorf("%s: element %d found beside its dot", tt.name, got)
+			}
+		}
+		if got := tt.l.element(universe(), 10*a, 0, 0); got != -1 {
+			t.Errorf("%s: element %d found far outside the lattice", tt.name, got)
+		}
+	}
+
+	// a basis keeps the order of the elements, each one followed by its copies
+	l := squareLattice(a, 2, 1).Basis(data.Vector{0, -25e-9, 0}, data.Vector{0, 25e-9, 0})
+	want := []data.Vector{{-a / 2, -25e-9, 0}, {-a / 2, 25e-9, 0}, {a / 2, -25e-9, 0}, {a / 2, 25e-9, 0}}
+	for e, p := range l.positions {
+		if p.Sub(want[e]).Len() > 1e-15 {
+			t.Errorf("element %d at %v, want %v", e, p, want[e])
+		}
+	}
+}
+
+func TestPolygon(t *testing.T) {
+	triangle := polygon(data.Vector{0, 0, 0}, data.Vector{4, 0, 0}, data.Vector{0, 4, 0})
+	// a pentagram, whose center is outside by the even-odd rule
+	star := polygon(data.Vector{0, 3, 0}, data.Vector{1.76, -2.43, 0}, data.Vector{-2.85, 0.93, 0},
+		data.Vector{2.85, 0.93, 0}, data.Vector{-1.76, -2.43, 0})
+	tests := []struct {
+		name string
+		s    shape
+		x, y float64
+		want bool
+	}{
+		{"triangle", triangle, 1, 1, true},
+		{"triangle", triangle, 3, 3, false},
+		{"triangle", triangle, -1, 1, false},
+		{"star", star, 0, 0, false},
+		{"star", star, 0, 2.5, true},
+		{"star", star, 0, -3, false},
+	}
+	for _, tt := range tests {
+		if got := tt.s(tt.x, tt.y, 0); got != tt.want {
+			t.Errorf("%s: (%g, %g) inside: %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
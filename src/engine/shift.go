@@ -28,7 +28,7 @@ func shift(dx int) {
 	if shiftGeom {
 		Geometry.shift(dx)
 	}
-	NormMag.normalize()
+	NormMag.renormalize()
 }
 
 func shiftMag(m *data.Slice, dx int) {
@@ -57,7 +57,7 @@ func yShift(dy int) {
 	if shiftGeom {
 		Geometry.shiftY(dy)
 	}
-	NormMag.normalize()
+	NormMag.renormalize()
 }
 
 func shiftMagY(m *data.Slice, dy int) {
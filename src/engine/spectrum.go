@@ -0,0 +1,253 @@
+package engine
+
+// On-the-fly Fourier transforms: a quantity is sampled periodically during the run and its temporal
+// spectrum accumulated on selected frequencies, optionally also transformed along one axis to get the
+// dispersion relation. The complex spectra are written to zarr at the end of the run.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/zstd"
+
+	"github.com/MathieuMoalic/amumax/src/cuda"
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/fsutil"
+	"github.com/MathieuMoalic/amumax/src/hooks"
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/zarr"
+)
+
+var spectra []*spectrum
+
+func init() {
+	DeclFunc("AutoSpectrum", autoSpectrum, "AutoSpectrum(q, frequencies, period) samples q every period (s) and accumulates "+
+		"its Fourier transform on the given frequencies (Hz), written to <q>_spectrum at the end of the run")
+	DeclFunc("AutoDispersion", autoDispersion, "AutoDispersion(q, frequencies, axis, period) is AutoSpectrum with an "+
+		"additional spatial Fourier transform along axis (\"x\", \"y\" or \"z\"), written to <q>_dispersion")
+	DeclFunc("SaveSpectra", saveSpectra, "Write the spectra accumulated so far, they are otherwise written at the end of the run")
+}
+
+// spectrum accumulates S(f) = Σ q(t) exp(-2πi f (t-t0)) Δt over the samples, for every cell and component.
+type spectrum struct {
+	name     string
+	q        Quantity
+	freqs    []float64
+	axis     int // axis of the spatial transform, -1 for none
+	period   float64
+	nextTime float64
+	t0, t1   float64
+	samples  int
+	size     [3]int
+	ncomp    int
+	acc      [][]complex64 // [frequency][cell*ncomp+comp]
+}
+
+func newSpectrum(q Quantity, name string, freqs []float64, axis int, period float64) *spectrum {
+	if period <= 0 {
+		log.Log.ErrAndExit("%s: the sampling period must be positive, got %g", name, period)
+	}
+	for _, f := range freqs {
+		if f > 0.5/period {
+			log.Log.Warn("%s: frequency %g Hz is above the Nyquist frequency %g Hz of the sampling period", name, f, 0.5/period)
+			break
+		}
+	}
+	s := &spectrum{name: name, q: q, freqs: freqs, axis: axis, period: period, size: sizeOf(q), ncomp: q.NComp()}
+	s.reset()
+	bytes := float64(len(freqs)*prod(s.size)*s.ncomp) * 8
+	log.Log.Info("%s: accumulating %d frequencies, using %.1f MB of host memory", name, len(freqs), bytes/1e6)
+	return s
+}
+
+// reset discards the accumulated spectrum and starts sampling at the current time.
+func (s *spectrum) reset() {
+	s.acc = make([][]complex64, len(s.freqs))
+	for i := range s.acc {
+		s.acc[i] = make([]complex64, prod(s.size)*s.ncomp)
+	}
+	s.t0, s.t1, s.nextTime, s.samples = Time, Time, Time, 0
+}
+
+func (s *spectrum) needSample() bool {
+	return Time >= s.nextTime
+}
+
+func (s *spectrum) sample() {
+	buffer := ValueOf(s.q)
+	host := buffer.HostCopy()
+	cuda.Recycle(buffer)
+	values := host.Host()
+	// weight by the time since the previous sample, adaptive steps do not land exactly on the period
+	dt := s.period
+	if s.samples > 0 {
+		dt = Time - s.t1
+	}
+	t := Time - s.t0
+
+	var wg sync.WaitGroup
+	jobs := make(chan int, len(s.freqs))
+	for i := range s.freqs {
+		jobs <- i
+	}
+	close(jobs)
+	for range min(len(s.freqs), runtime.GOMAXPROCS(0)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				phase := -2 * math.Pi * s.freqs[i] * t
+				w := complex64(complex(math.Cos(phase)*dt, math.Sin(phase)*dt))
+				acc := s.acc[i]
+				for c, comp := range values {
+					for cell, v := range comp {
+						acc[cell*s.ncomp+c] += w * complex(v, 0)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	s.samples++
+	s.t1 = Time
+	for s.nextTime <= Time {
+		s.nextTime += s.period
+	}
+}
+
+// transformed returns a copy of the accumulated spectrum, Fourier transformed along the spatial axis if any.
+func (s *spectrum) transformed() [][]complex64 {
+	out := make([][]complex64, len(s.acc))
+	for i := range s.acc {
+		out[i] = append([]complex64(nil), s.acc[i]...)
+	}
+	if s.axis < 0 {
+		return out
+	}
+	n := s.size[s.axis]
+	stride := s.ncomp
+	for a := 0; a < s.axis; a++ {
+		stride *= s.size[a]
+	}
+	line := make([]complex128, n)
+	for _, f := range out {
+		for start := range f {
+			// start is the first element of a line along the axis
+			if (start/stride)%n != 0 {
+				continue
+			}
+			for j := range line {
+				line[j] = complex128(f[start+j*stride])
+			}
+			for j, v := range data.FFT(line) {
+				f[start+j*stride] = complex64(v)
+			}
+		}
+	}
+	return out
+}
+
+// wavenumbers returns the angular wave numbers (rad/m) of the spatial transform, in FFT order.
+func (s *spectrum) wavenumbers() []float64 {
+	if s.axis < 0 {
+		return nil
+	}
+	k := data.FFTFreq(s.size[s.axis], GetMesh().CellSize()[s.axis])
+	for i := range k {
+		k[i] *= 2 * math.Pi
+	}
+	return k
+}
+
+// save writes the spectrum accumulated so far as a complex64 array of shape [f, z, y, x, comp].
+func (s *spectrum) save() {
+	if s.samples == 0 {
+		return
+	}
+	values := s.transformed()
+	attrs := map[string]any{
+		"f":       s.freqs,
+		"t0":      s.t0,
+		"t1":      s.t1,
+		"period":  s.period,
+		"samples": s.samples,
+		"unit":    strings.TrimSpace(unitOf(s.q) + " s"),
+	}
+	if s.axis >= 0 {
+		attrs["axis"] = []string{"x", "y", "z"}[s.axis]
+		attrs["k"] = s.wavenumbers()
+	}
+	name, size, ncomp := s.name, s.size, s.ncomp
+	hp := hookPayload(hooks.Save, name, OD()+name)
+	queOutput(func() {
+		if !fsutil.Exists(OD() + name) {
+			log.Log.PanicIfError(fsutil.Mkdir(OD() + name))
+		}
+		zarr.SaveFileSpectrumZarray(OD()+name+"/.zarray", size, ncomp, len(values))
+		zarr.SaveZattrs(OD()+name, attrs)
+		raw := make([]byte, 8*len(values[0]))
+		for i, f := range values {
+			for j, v := range f {
+				binary.LittleEndian.PutUint32(raw[8*j:], math.Float32bits(real(v)))
+				binary.LittleEndian.PutUint32(raw[8*j+4:], math.Float32bits(imag(v)))
+			}
+			compressed, err := zstd.Compress(nil, raw)
+			log.Log.PanicIfError(err)
+			log.Log.PanicIfError(fsutil.Put(fmt.Sprintf("%s%s/%d.0.0.0.0", OD(), name, i), compressed))
+		}
+		Hooks.Fire(hp)
+	})
+}
+
+func addSpectrum(s *spectrum) {
+	for i, old := range spectra {
+		if old.name == s.name {
+			log.Log.Warn("%s was already accumulated, restarting it.", s.name)
+			spectra[i] = s
+			return
+		}
+	}
+	spectra = append(spectra, s)
+}
+
+func autoSpectrum(q Quantity, freqs InputArray, period float64) {
+	addSpectrum(newSpectrum(q, nameOf(q)+"_spectrum", freqs.data, -1, period))
+}
+
+func autoDispersion(q Quantity, freqs InputArray, axis string, period float64) {
+	a := strings.Index("xyz", strings.ToLower(axis))
+	if len(axis) != 1 || a < 0 {
+		log.Log.ErrAndExit("AutoDispersion: axis must be \"x\", \"y\" or \"z\", got `%s`", axis)
+	}
+	addSpectrum(newSpectrum(q, nameOf(q)+"_dispersion", freqs.data, a, period))
+}
+
+// sampleSpectraIfNeeded is called by the run loop next to the other periodic output.
+func sampleSpectraIfNeeded() {
+	for _, s := range spectra {
+		if s.needSample() {
+			s.sample()
+		}
+	}
+}
+
+func saveSpectra() {
+	for _, s := range spectra {
+		s.save()
+	}
+}
+
+// restartSpectra returns empty spectra with the same settings, starting at the current time.
+func restartSpectra(list []*spectrum) []*spectrum {
+	var out []*spectrum
+	for _, s := range list {
+		c := *s
+		c.reset()
+		out = append(out, &c)
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package engine
+
+// Parameter sweeps inside a single process: the mesh, kernels and GPU context are reused,
+// each run starts from the same magnetization and writes into its own zarr subgroup.
+
+import (
+	"fmt"
+
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/zarr"
+)
+
+func init() {
+	DeclFunc("Sweep", sweep, "Sweep(name, values, func(x float64){...}) runs the function for each value, "+
+		"starting every run from the current m and time, and writes its output to the zarr group name/<index>")
+}
+
+// outputState is the output bookkeeping that is swapped out while a sweep run writes to its subgroup.
+type outputState struct {
+	od              string
+	savedQuantities []savedQuantity
+	table           tableState
+	tableGroups     []*tableStruct
+	output          map[Quantity]*autosave
+	autonum         map[string]int
+	spectra         []*spectrum
+}
+
+func stashOutput() outputState {
+	s := outputState{
+		od:              outputdir,
+		savedQuantities: savedQuantities.Quantities,
+		table:           Table.state(),
+		tableGroups:     tableGroups,
+		output:          make(map[Quantity]*autosave),
+		autonum:         autonum,
+		spectra:         spectra,
+	}
+	for q, a := range output {
+		s.output[q] = a
+	}
+	return s
+}
+
+func restoreOutput(s outputState) {
+	outputdir = s.od
+	savedQuantities.Quantities = s.savedQuantities
+	Table.setState(s.table)
+	setTableGroups(s.tableGroups)
+	output = s.output
+	autonum = s.autonum
+	spectra = s.spectra
+}
+
+// startSweepRun redirects all output to od, with the same saved quantities, table columns and
+// autosave periods as the main run but no saved data yet.
+func startSweepRun(root outputState, od string) {
+	outputdir = od
+	savedQuantities.restart(root.savedQuantities)
+	Table.restart(root.table)
+	setTableGroups(restartTableGroups(root.tableGroups))
+	output = make(map[Quantity]*autosave)
+	for q, a := range root.output {
+		output[q] = &autosave{a.period, Time, -1, a.save}
+	}
+	autonum = make(map[string]int)
+	spectra = restartSpectra(root.spectra)
+}
+
+func endSweepRun() {
+	saveSpectra()
+	drainOutput()
+	Table.close()
+	closeTableGroups()
+}
+
+func sweep(name string, values InputArray, body func(float64)) {
+	if values.Len() == 0 {
+		log.Log.Warn("Sweep %s: no values given. Ignoring.", name)
+		return
+	}
+	drainOutput()
+	flushTables()
+	root := stashOutput()
+	m0 := NormMag.Buffer().HostCopy()
+	t0, dt0 := Time, DtSi
+	defer func() {
+		restoreOutput(root)
+		NormMag.restore(m0)
+		Time, DtSi = t0, dt0
+	}()
+
+	zarr.InitZgroup(name, root.od)
+	zarr.SaveZattrs(root.od+name, map[string]any{"parameter": name, "values": values.data})
+	for i, v := range values.data {
+		group := fmt.Sprintf("%s/%03d", name, i)
+		log.Log.Info("Sweep %s: run %d/%d with %s = %g in %s", name, i+1, values.Len(), name, v, group)
+		zarr.InitZgroup(group, root.od)
+		zarr.SaveZattrs(root.od+group, map[string]any{"index": i, name: v, "t0": t0})
+		NormMag.restore(m0)
+		Time, DtSi = t0, dt0
+		startSweepRun(root, root.od+group+"/")
+		body(v)
+		endSweepRun()
+	}
+}
@@ -0,0 +1,152 @@
+package engine
+
+// Table groups: additional tables with their own columns, written at their own period or when a
+// condition becomes true, each in its own zarr group table/<name>.
+
+import (
+	"sync"
+	"time"
+
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/zarr"
+)
+
+var (
+	tableGroups   []*tableStruct
+	tableGroupsMu sync.Mutex
+)
+
+func init() {
+	DeclFunc("TableGroupAdd", tableGroupAdd, "TableGroupAdd(group, q) adds q to the table group written in table/<group>")
+	DeclFunc("TableGroupAutoSave", tableGroupAutoSave, "TableGroupAutoSave(group, period) writes a row of the "+
+		"table group every period (s), independently of the main table")
+	DeclFunc("TableGroupTrigger", tableGroupTrigger, "TableGroupTrigger(group, condition) writes a row of the "+
+		"table group at every step where condition becomes true, e.g. TableGroupTrigger(\"switch\", m.Comp(2).Average() < 0)")
+	DeclFunc("TableGroupSave", tableGroupSave, "TableGroupSave(group) writes a row of the table group right now")
+}
+
+// tableGroup returns the table group called name, creating it if needed.
+func tableGroup(name string) *tableStruct {
+	tableGroupsMu.Lock()
+	defer tableGroupsMu.Unlock()
+	for _, g := range tableGroups {
+		if g.name == "table/"+name {
+			return g
+		}
+	}
+	if name == "" {
+		log.Log.ErrAndExit("Table groups need a name")
+	}
+	if len(Table.Columns) == 0 {
+		// the main table clears the table directory when it is created
+		tableInit()
+	}
+	g := &tableStruct{
+		name:          "table/" + name,
+		Data:          make(map[string][]float64),
+		Step:          -1,
+		FlushInterval: 5 * time.Second,
+	}
+	zarr.InitZgroup(g.name, OD())
+	g.AddColumn("step", "")
+	g.AddColumn("t", "s")
+	tableGroups = append(tableGroups, g)
+	return g
+}
+
+func tableGroupAdd(group string, q Quantity) {
+	g := tableGroup(group)
+	if g.Step != -1 {
+		log.Log.Warn("You cannot add a new quantity to the table group %s after it was first written. Ignoring.", group)
+		return
+	}
+	names := columnNames(q, nameOf(q), "")
+	if g.exists(names) {
+		log.Log.Warn("%s is already in the table group %s. Ignoring.", nameOf(q), group)
+		return
+	}
+	g.quantities = append(g.quantities, q)
+	for _, n := range names {
+		g.AddColumn(n, unitOf(q))
+	}
+}
+
+func tableGroupAutoSave(group string, period float64) {
+	g := tableGroup(group)
+	g.AutoSaveStart = Time
+	g.AutoSavePeriod = period
+	zarr.SaveZattrs(OD()+g.name, g.attrs())
+}
+
+func tableGroupTrigger(group string, condition func() bool) {
+	g := tableGroup(group)
+	g.trigger = condition
+	g.triggered = condition()
+	zarr.SaveZattrs(OD()+g.name, g.attrs())
+}
+
+func tableGroupSave(group string) {
+	tableGroup(group).save()
+}
+
+func (ts *tableStruct) save() {
+	ts.Step += 1
+	ts.WriteToBuffer()
+}
+
+func (ts *tableStruct) attrs() map[string]any {
+	return map[string]any{"period": ts.AutoSavePeriod, "triggered": ts.trigger != nil}
+}
+
+// saveTableGroupsIfNeeded is called by the run loop next to the main table.
+func saveTableGroupsIfNeeded() {
+	for _, g := range tableGroups {
+		save := g.NeedSave()
+		if g.trigger != nil {
+			// rows are only written when the condition changes from false to true
+			triggered := g.trigger()
+			save = save || (triggered && !g.triggered)
+			g.triggered = triggered
+		}
+		if save {
+			g.save()
+		}
+	}
+}
+
+// flushTables flushes the main table and the table groups.
+func flushTables() {
+	Table.Flush()
+	tableGroupsMu.Lock()
+	defer tableGroupsMu.Unlock()
+	for _, g := range tableGroups {
+		g.Flush()
+	}
+}
+
+// restartTableGroups returns empty table groups with the same columns and periods in the current output directory.
+func restartTableGroups(groups []*tableStruct) []*tableStruct {
+	var out []*tableStruct
+	for _, g := range groups {
+		n := &tableStruct{name: g.name, FlushInterval: g.FlushInterval, trigger: g.trigger}
+		n.restart(g.state())
+		n.triggered = n.trigger != nil && n.trigger()
+		zarr.SaveZattrs(OD()+n.name, n.attrs())
+		out = append(out, n)
+	}
+	return out
+}
+
+func closeTableGroups() {
+	tableGroupsMu.Lock()
+	defer tableGroupsMu.Unlock()
+	for _, g := range tableGroups {
+		g.close()
+	}
+}
+
+func setTableGroups(groups []*tableStruct) {
+	tableGroupsMu.Lock()
+	defer tableGroupsMu.Unlock()
+	tableGroups = groups
+}
@@ -0,0 +1,67 @@
+package engine
+
+// Table columns averaged over part of the sample: per region, or inside a shape.
+
+import (
+	"fmt"
+
+	"github.com/MathieuMoalic/amumax/src/cuda"
+	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/mesh"
+)
+
+func init() {
+	DeclFunc("TableAddRegions", tableAddRegions, "TableAddRegions(q, regions...) adds the average of q over each "+
+		"of the given regions to the table, in columns named like m.region3.x")
+	DeclFunc("TableAddMasked", tableAddMasked, "TableAddMasked(q, shape, name) adds the average of q over the "+
+		"magnet cells inside shape to the table, in columns named like m.name.x")
+}
+
+func tableAddRegions(q Quantity, regions ...int) {
+	if len(regions) == 0 {
+		log.Log.Warn("TableAddRegions: no regions given. Ignoring.")
+		return
+	}
+	for _, r := range regions {
+		if r < 0 || r >= NREGION {
+			log.Log.ErrAndExit("TableAddRegions: region %d out of range [0, %d)", r, NREGION)
+		}
+		rq := inRegion(q, r)
+		tableAddColumns(rq, nameOf(rq), ".")
+	}
+}
+
+func tableAddMasked(q Quantity, s shape, name string) {
+	tableAddColumns(&maskAverage{masked{q, s, nil, mesh.Mesh{}}, name}, nameOf(q)+"."+name, ".")
+}
+
+// maskAverage is q inside a shape, 0 outside, whose average is taken over the magnet cells inside the shape only.
+type maskAverage struct {
+	masked
+	name string
+}
+
+func (q *maskAverage) Name() string     { return fmt.Sprint(nameOf(q.orig), ".", q.name) }
+func (q *maskAverage) Unit() string     { return unitOf(q.orig) }
+func (q *maskAverage) Mesh() *mesh.Mesh { return MeshOf(q.orig) }
+
+func (q *maskAverage) average() []float64 {
+	buf := cuda.Buffer(q.NComp(), q.Mesh().Size())
+	defer cuda.Recycle(buf)
+	q.EvalTo(buf) // also updates the mask
+	avg := sAverageMagnet(buf)
+	if v := q.volume(); v > 0 {
+		sDiv(avg, v)
+	}
+	return avg
+}
+
+// volume returns the fraction of the magnet inside the shape.
+func (q *maskAverage) volume() float64 {
+	if Geometry.Gpu().IsNil() {
+		return float64(cuda.Sum(q.mask)) / float64(GetMesh().NCell())
+	}
+	return float64(cuda.Dot(q.mask, Geometry.Gpu())) / magnetNCell()
+}
+
+func (q *maskAverage) Average() []float64 { return q.average() }
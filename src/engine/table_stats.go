@@ -0,0 +1,243 @@
+package engine
+
+// Table columns with statistics of a quantity other than its average.
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MathieuMoalic/amumax/src/cuda"
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/log"
+)
+
+func init() {
+	DeclFunc("TableAddStats", tableAddStats, "TableAddStats(q, stats...) adds statistics of q to the table: \"min\", "+
+		"\"max\", \"std\", \"maxabs\", \"maxnorm\", \"maxpos\" or a percentile like \"p95\", in columns named like m.max.x")
+}
+
+// geomInside caches whether each cell is inside the geometry, for the statistics computed on the host.
+var geomInside struct {
+	cells   []bool
+	version int
+	size    [3]int
+}
+
+// tableStat is one statistic of a quantity, evaluated when a table row is written.
+type tableStat struct {
+	q          Quantity
+	stat       string
+	percentile float64
+}
+
+func tableAddStats(q Quantity, stats ...string) {
+	if len(stats) == 0 {
+		log.Log.Warn("TableAddStats: no statistics given. Ignoring.")
+		return
+	}
+	for _, stat := range stats {
+		s := newTableStat(q, strings.ToLower(stat))
+		tableAddColumns(s, s.Name(), ".")
+	}
+}
+
+func newTableStat(q Quantity, stat string) *tableStat {
+	s := &tableStat{q: q, stat: stat}
+	switch stat {
+	case "min", "max", "std", "maxabs", "maxpos":
+	case "maxnorm":
+		if q.NComp() != 3 {
+			log.Log.ErrAndExit("TableAddStats: maxnorm needs a vector quantity, %s has %d components", nameOf(q), q.NComp())
+		}
+	default:
+		p, err := strconv.ParseFloat(strings.TrimPrefix(stat, "p"), 64)
+		if !strings.HasPrefix(stat, "p") || err != nil || math.IsNaN(p) || p < 0 || p > 100 {
+			log.Log.ErrAndExit("TableAddStats: unknown statistic `%s`, expected min, max, std, maxabs, "+
+				"maxnorm, maxpos or a percentile like p95", stat)
+		}
+		s.percentile = p
+	}
+	return s
+}
+
+// Name is the column name, without dots in the statistic so that the table groups the columns by quantity,
+// e.g. Edens.p99_9 for the percentile p99.9.
+func (s *tableStat) Name() string { return nameOf(s.q) + "." + strings.ReplaceAll(s.stat, ".", "_") }
+
+func (s *tableStat) Unit() string {
+	if s.stat == "maxpos" {
+		return "m"
+	}
+	return unitOf(s.q)
+}
+
+func (s *tableStat) NComp() int {
+	switch s.stat {
+	case "maxnorm":
+		return 1
+	case "maxpos":
+		return 3
+	}
+	return s.q.NComp()
+}
+
+func (s *tableStat) EvalTo(dst *data.Slice) {
+	v := s.average()
+	for c := 0; c < s.NComp(); c++ {
+		cuda.Memset(dst.Comp(c), float32(v[c]))
+	}
+}
+
+// average returns the statistic, it is what the table records.
+// maxabs and maxnorm are reduced on the GPU over the whole mesh, std is weighted by the geometry like the
+// averages, min and max are reduced on the GPU over the cells inside the geometry, and the percentiles and
+// maxpos are computed on the host over the cells inside the geometry.
+func (s *tableStat) average() []float64 {
+	buf := ValueOf(s.q)
+	defer cuda.Recycle(buf)
+	switch s.stat {
+	case "maxabs":
+		out := make([]float64, buf.NComp())
+		for c := range out {
+			out[c] = float64(cuda.MaxAbs(buf.Comp(c)))
+		}
+		return out
+	case "maxnorm":
+		return []float64{cuda.MaxVecNorm(buf)}
+	case "std":
+		return s.std(buf)
+	case "min", "max":
+		return s.extremum(buf, s.stat == "max")
+	}
+
+	host := buf.HostCopy()
+	inside := s.insideCells()
+	switch s.stat {
+	case "maxpos":
+		return hostMaxPosition(host, inside)
+	}
+	return hostPercentile(host, inside, s.percentile)
+}
+
+// std returns the standard deviation of each component, weighted by the geometry.
+func (s *tableStat) std(buf *data.Slice) []float64 {
+	average := sAverageMagnet
+	if sizeOf(s.q) != meshSize() {
+		average = sAverageUniverse
+	}
+	sq := cuda.Buffer(1, buf.Size())
+	defer cuda.Recycle(sq)
+	mean := average(buf)
+	out := make([]float64, buf.NComp())
+	for c := range out {
+		cuda.Mul(sq, buf.Comp(c), buf.Comp(c))
+		out[c] = math.Sqrt(math.Max(0, average(sq)[0]-mean[c]*mean[c]))
+	}
+	return out
+}
+
+// extremum returns the minimum or maximum of each component over the cells inside the geometry. It is
+// reduced with MaxAbs on values shifted to be positive, max(x) = MaxAbs(x + M) - M and
+// min(x) = M - MaxAbs(M - x) with M = MaxAbs(x), which costs a relative precision of about 1e-7 of M.
+func (s *tableStat) extremum(buf *data.Slice, max bool) []float64 {
+	var inside *data.Slice
+	if sizeOf(s.q) == meshSize() && !Geometry.Gpu().IsNil() {
+		inside = insideGeometry()
+		defer cuda.Recycle(inside)
+	}
+	shifted := cuda.Buffer(1, buf.Size())
+	defer cuda.Recycle(shifted)
+	out := make([]float64, buf.NComp())
+	for c := range out {
+		M := cuda.MaxAbs(buf.Comp(c))
+		cuda.Memset(shifted, M)
+		if max {
+			cuda.Madd2(shifted, buf.Comp(c), shifted, 1, 1)
+		} else {
+			cuda.Madd2(shifted, shifted, buf.Comp(c), 1, -1)
+		}
+		if inside != nil {
+			cuda.Mul(shifted, shifted, inside) // 0 outside, which is the smallest shifted value
+		}
+		r := float64(cuda.MaxAbs(shifted))
+		if max {
+			out[c] = r - float64(M)
+		} else {
+			out[c] = float64(M) - r
+		}
+	}
+	return out
+}
+
+// insideCells returns whether each cell is inside the geometry, or nil if all cells count.
+func (s *tableStat) insideCells() []bool {
+	if Geometry.Gpu().IsNil() || sizeOf(s.q) != meshSize() {
+		return nil
+	}
+	c := &geomInside
+	if c.cells == nil || c.version != Geometry.version || c.size != meshSize() {
+		geom := Geometry.Gpu().HostCopy().Host()[0]
+		c.cells = make([]bool, len(geom))
+		for i, g := range geom {
+			c.cells[i] = g > 0
+		}
+		c.version, c.size = Geometry.version, meshSize()
+	}
+	return c.cells
+}
+
+// hostMaxPosition returns the position of the cell with the largest value, or largest norm for a vector.
+func hostMaxPosition(f *data.Slice, inside []bool) []float64 {
+	values := f.Host()
+	best, bestCell := math.Inf(-1), -1
+	for i := range values[0] {
+		if inside != nil && !inside[i] {
+			continue
+		}
+		v := float64(values[0][i])
+		if f.NComp() > 1 {
+			v = 0
+			for c := range values {
+				v += float64(values[c][i]) * float64(values[c][i])
+			}
+		}
+		if v > best {
+			best, bestCell = v, i
+		}
+	}
+	if bestCell < 0 {
+		return []float64{math.NaN(), math.NaN(), math.NaN()}
+	}
+	size := f.Size()
+	ix, iy, iz := bestCell%size[X], (bestCell/size[X])%size[Y], bestCell/(size[X]*size[Y])
+	r := index2Coord(ix, iy, iz)
+	return []float64{r[X], r[Y], r[Z]}
+}
+
+// hostPercentile returns the p-th percentile of each component, interpolating linearly between cells.
+func hostPercentile(f *data.Slice, inside []bool, p float64) []float64 {
+	out := make([]float64, f.NComp())
+	for c, comp := range f.Host() {
+		values := make([]float64, 0, len(comp))
+		for i, v := range comp {
+			if inside == nil || inside[i] {
+				values = append(values, float64(v))
+			}
+		}
+		out[c] = percentile(values, p)
+	}
+	return out
+}
+
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sort.Float64s(values)
+	pos := p / 100 * float64(len(values)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(values)-1)
+	return values[lo] + (pos-float64(lo))*(values[hi]-values[lo])
+}
@@ -37,7 +37,7 @@ func init() {
 }
 
 func (b *thermField) AddTo(dst *data.Slice) {
-	if !Temp.isZero() {
+	if !zeroTemperature() && !llbMode { // the temperature enters the LLB equation through its coefficients
 		b.update()
 		cuda.Add(dst, dst, b.noise)
 	}
@@ -61,7 +61,7 @@ func (b *thermField) update() {
 		BTherm.dt = -1
 	}
 
-	if Temp.isZero() {
+	if zeroTemperature() {
 		cuda.Memset(b.noise, 0, 0, 0)
 		b.step = NSteps
 		b.dt = DtSi
@@ -98,7 +98,7 @@ func (b *thermField) update() {
 	dst := b.noise
 	ms := Msat.MSlice()
 	defer ms.Recycle()
-	temp := Temp.MSlice()
+	temp := thermalTemperature()
 	defer temp.Recycle()
 	alpha := Alpha.MSlice()
 	defer alpha.Recycle()
@@ -112,7 +112,7 @@ func (b *thermField) update() {
 }
 
 func getThermalEnergy() float64 {
-	if Temp.isZero() || relaxing {
+	if zeroTemperature() || relaxing {
 		return 0
 	}
 	return -cellVolume() * dot(&MFull, &BTherm)
@@ -0,0 +1,170 @@
+package engine
+
+// Temperature dependence of region parameters. A law multiplies the value set for each region by a factor
+// of the temperature Temp of that region, and is re-evaluated whenever Temp changes.
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MathieuMoalic/amumax/src/fsutil"
+	"github.com/MathieuMoalic/amumax/src/log"
+)
+
+func init() {
+	DeclFunc("BlochLaw", blochLaw, "BlochLaw(Tc, exponent) is the temperature law 1 - (T/Tc)^exponent below the "+
+		"Curie temperature Tc (K) and 0 above, e.g. BlochLaw(Tc, 1.5) for Msat. Use law.Pow(n) for the Callen-Callen "+
+		"scaling of Aex (n = 2) or Ku1 (n = 3)")
+	DeclFunc("TempTable", tempTable, "TempTable(file) is the temperature law interpolated linearly from a file with "+
+		"one `T factor` pair per line, constant beyond the first and last temperatures")
+}
+
+// temperatureLaw is a factor as a function of the temperature (K).
+type temperatureLaw struct {
+	factor func(T float64) float64
+	desc   string
+}
+
+// tempDependence is a temperature law attached to a parameter. The region values set by the user are kept in
+// base, the lookup table of the parameter holds base times the law at the temperature of each region.
+type tempDependence struct {
+	law  *temperatureLaw
+	base [][NREGION]float32
+	ok   bool // lookup table up to date with base and Temp
+}
+
+func blochLaw(tc, exponent float64) *temperatureLaw {
+	if tc <= 0 {
+		log.Log.ErrAndExit("BlochLaw: the Curie temperature should be positive, have %v", tc)
+	}
+	return &temperatureLaw{
+		factor: func(T float64) float64 {
+			if T >= tc {
+				return 0
+			}
+			return 1 - math.Pow(math.Max(T, 0)/tc, exponent)
+		},
+		desc: fmt.Sprintf("BlochLaw(%g, %g)", tc, exponent),
+	}
+}
+
+func tempTable(fname string) *temperatureLaw {
+	temps, factors := readTempTable(fname)
+	return &temperatureLaw{
+		factor: func(T float64) float64 {
+			i := sort.SearchFloat64s(temps, T)
+			switch {
+			case i == 0:
+				return factors[0]
+			case i == len(temps):
+				return factors[len(factors)-1]
+			}
+			t := (T - temps[i-1]) / (temps[i] - temps[i-1])
+			return (1-t)*factors[i-1] + t*factors[i]
+		},
+		desc: fmt.Sprintf("TempTable(%s)", fname),
+	}
+}
+
+// readTempTable reads `T factor` pairs, one per line, with increasing temperatures. Empty lines and lines
+// starting with # are skipped.
+func readTempTable(fname string) (temps, factors []float64) {
+	r, err := fsutil.Open(fname)
+	log.Log.PanicIfError(err)
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Log.PanicIfError(err)
+		}
+	}()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			log.Log.ErrAndExit("%s:%d: expected `T factor`, got `%s`", fname, line, text)
+		}
+		T, err1 := strconv.ParseFloat(fields[0], 64)
+		f, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 != nil || err2 != nil {
+			log.Log.ErrAndExit("%s:%d: temperature and factor should be numbers, got `%s`", fname, line, text)
+		}
+		if len(temps) > 0 && T <= temps[len(temps)-1] {
+			log.Log.ErrAndExit("%s:%d: the temperatures should be increasing, %v follows %v", fname, line, T, temps[len(temps)-1])
+		}
+		temps = append(temps, T)
+		factors = append(factors, f)
+	}
+	log.Log.PanicIfError(scanner.Err())
+	if len(temps) == 0 {
+		log.Log.ErrAndExit("TempTable: %s has no `T factor` pairs", fname)
+	}
+	return temps, factors
+}
+
+// Pow returns the law raised to the power n, e.g. m(T)^2 for the exchange stiffness.
+func (l *temperatureLaw) Pow(n float64) *temperatureLaw {
+	return &temperatureLaw{
+		factor: func(T float64) float64 { return math.Pow(l.factor(T), n) },
+		desc:   fmt.Sprintf("%s.Pow(%g)", l.desc, n),
+	}
+}
+
+// SetTempLaw multiplies the parameter in each region by the law at the temperature Temp of the region. The values
+// set for the parameter are the ones at the reference of the law, e.g. 0 K for BlochLaw.
+func (p *regionwiseScalar) SetTempLaw(law *temperatureLaw) {
+	if p == Temp {
+		log.Log.ErrAndExit("Temp cannot depend on the temperature")
+	}
+	if heatDiffusion {
+		log.Log.ErrAndExit("%s: the temperature laws follow Temp, they cannot be used with HeatDiffusion", p.name)
+	}
+	if p.tempDep == nil {
+		base := make([][NREGION]float32, p.NComp())
+		copy(base, p.cpuLUT())
+		p.tempDep = &tempDependence{base: base}
+		Temp.children = append(Temp.children, p)
+	}
+	p.tempDep.law = law
+	p.invalidate()
+	log.Log.Info("%s depends on the temperature through %s", p.name, law.desc)
+}
+
+// ClearTempLaw removes the temperature law, the parameter takes the values set for it again.
+func (p *regionwiseScalar) ClearTempLaw() {
+	if p.tempDep == nil {
+		return
+	}
+	copy(p.cpuBuf, p.tempDep.base)
+	p.tempDep = nil
+	for i, c := range Temp.children {
+		if c == derived(p) {
+			Temp.children = append(Temp.children[:i], Temp.children[i+1:]...)
+			break
+		}
+	}
+	p.invalidate()
+}
+
+// updateTemp recomputes the lookup table from the base values if they or Temp changed.
+func (p *regionwise) updateTemp() {
+	temp := Temp.cpuLUT()[0] // brings Temp up to date, which invalidates p if Temp changed
+	if p.tempDep.ok {
+		return
+	}
+	for c := range p.cpuBuf {
+		for r := 0; r < NREGION; r++ {
+			p.cpuBuf[c][r] = float32(float64(p.tempDep.base[c][r]) * p.tempDep.law.factor(float64(temp[r])))
+		}
+	}
+	p.invalidate()
+	p.tempDep.ok = true
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTempTable(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "msat.txt")
+	table := "# T factor\n\n100 1\n  300 0.5  \n# above Tc\n700 0\n"
+	if err := os.WriteFile(fname, []byte(table), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	temps, factors := readTempTable(fname)
+	if !slices.Equal(temps, []float64{100, 300, 700}) || !slices.Equal(factors, []float64{1, 0.5, 0}) {
+		t.Fatalf("read %v and %v, want the three pairs without the comments and empty lines", temps, factors)
+	}
+
+	law := tempTable(fname)
+	tests := []struct {
+		T, want float64
+	}{
+		{0, 1},      // constant below the first temperature
+		{100, 1},    // at a point
+		{200, 0.75}, // halfway
+		{300, 0.5},
+		{400, 0.375},
+		{700, 0},
+		{1000, 0}, // constant above the last temperature
+	}
+	for _, tt := range tests {
+		if got := law.factor(tt.T); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("factor(%g K) = %g, want %g", tt.T, got, tt.want)
+		}
+	}
+}
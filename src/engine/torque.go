@@ -45,6 +45,10 @@ func setTorque(dst *data.Slice) {
 
 // Sets dst to the current Landau-Lifshitz torque
 func setLLTorque(dst *data.Slice) {
+	if llbMode {
+		setLLBTorque(dst)
+		return
+	}
 	setEffectiveField(dst) // calc and store B_eff
 	alpha := Alpha.MSlice()
 	defer alpha.Recycle()
@@ -7,6 +7,7 @@ import (
 	"github.com/MathieuMoalic/amumax/src/cuda"
 	"github.com/MathieuMoalic/amumax/src/data"
 	"github.com/MathieuMoalic/amumax/src/fsutil"
+	"github.com/MathieuMoalic/amumax/src/hooks"
 	"github.com/MathieuMoalic/amumax/src/log"
 	"github.com/MathieuMoalic/amumax/src/script"
 	"github.com/MathieuMoalic/amumax/src/zarr"
@@ -14,6 +15,7 @@ import (
 
 func init() {
 	Table = tableStruct{
+		name:           "table",
 		Data:           make(map[string][]float64),
 		Step:           -1,
 		AutoSavePeriod: 0.0,
@@ -25,6 +27,7 @@ var Table tableStruct
 
 // the Table is kept in RAM and used for the API
 type tableStruct struct {
+	name           string // zarr group of the table, relative to the output directory
 	quantities     []Quantity
 	Columns        []column
 	Data           map[string][]float64 `json:"data"`
@@ -33,6 +36,17 @@ type tableStruct struct {
 	Step           int                  `json:"step"`
 	FlushInterval  time.Duration        `json:"flushInterval"`
 	Mu             sync.Mutex
+	trigger        func() bool // writes a row when it becomes true, for table groups
+	triggered      bool        // last value of trigger
+	lastRow        rowInfo     // state of the run at the last row, for the on-table-flush hooks
+}
+
+// rowInfo is the state of the run when a row was written. Flush runs on the auto-flush goroutine too, so it
+// cannot read the simulation globals itself.
+type rowInfo struct {
+	step int
+	time float64
+	od   string
 }
 
 type column struct {
@@ -54,6 +68,7 @@ func (ts *tableStruct) WriteToBuffer() {
 	// size of buf should be same as size of []Ztable
 	ts.Mu.Lock() // Lock the mutex before modifying the map
 	defer ts.Mu.Unlock()
+	ts.lastRow = rowInfo{step: NSteps, time: Time, od: outputdir}
 	for i, b := range buf {
 		ts.Columns[i].buffer = append(ts.Columns[i].buffer, zarr.Float64ToBytes(b)...)
 		ts.Data[ts.Columns[i].Name] = append(ts.Data[ts.Columns[i].Name], b)
@@ -61,16 +76,30 @@ func (ts *tableStruct) WriteToBuffer() {
 }
 
 func (ts *tableStruct) Flush() {
+	ts.Mu.Lock()
+	defer ts.Mu.Unlock()
+	written := false
 	for i := range ts.Columns {
+		written = written || len(ts.Columns[i].buffer) > 0
 		_, err := ts.Columns[i].io.Write(ts.Columns[i].buffer)
 		log.Log.PanicIfError(err)
 		ts.Columns[i].buffer = []byte{}
 		// saving .zarray before the data might help resolve some unsync
 		// errors when the simulation is running and the user loads data
-		zarr.SaveFileTableZarray(OD()+"table/"+ts.Columns[i].Name, ts.Step)
+		zarr.SaveFileTableZarray(OD()+ts.name+"/"+ts.Columns[i].Name, ts.Step)
 		err = ts.Columns[i].io.Flush()
 		log.Log.PanicIfError(err)
 	}
+	if written {
+		row := ts.lastRow
+		Hooks.Fire(hooks.Payload{
+			Event: hooks.TableFlush,
+			OD:    row.od,
+			Step:  row.step,
+			Time:  row.time,
+			Files: []string{row.od + ts.name},
+		})
+	}
 }
 
 func (ts *tableStruct) NeedSave() bool {
@@ -78,31 +107,43 @@ func (ts *tableStruct) NeedSave() bool {
 }
 
 func (ts *tableStruct) Exists(q Quantity, name string) bool {
-	suffixes := []string{"x", "y", "z"}
-	for _, i := range Table.Columns {
-		if q.NComp() == 1 {
+	return ts.exists(columnNames(q, name, ""))
+}
+
+func (ts *tableStruct) exists(names []string) bool {
+	for _, i := range ts.Columns {
+		for _, name := range names {
 			if i.Name == name {
 				return true
 			}
-		} else {
-			for comp := 0; comp < q.NComp(); comp++ {
-				if name+suffixes[comp] == i.Name {
-					return true
-				}
-			}
 		}
 	}
 	return false
 }
 
+// columnNames returns the table columns of q: name for a scalar, or name+sep+"x", ... for each component.
+func columnNames(q Quantity, name, sep string) []string {
+	if q.NComp() == 1 {
+		return []string{name}
+	}
+	suffixes := []string{"x", "y", "z"}
+	names := make([]string, q.NComp())
+	for comp := range names {
+		names[comp] = name + sep + suffixes[comp]
+	}
+	return names
+}
+
 func (ts *tableStruct) AddColumn(name, unit string) {
-	err := fsutil.Mkdir(OD() + "table/" + name)
+	err := fsutil.Mkdir(OD() + ts.name + "/" + name)
 	log.Log.PanicIfError(err)
-	f, err := fsutil.Create(OD() + "table/" + name + "/0")
+	f, err := fsutil.Create(OD() + ts.name + "/" + name + "/0")
 	log.Log.PanicIfError(err)
 	ts.Columns = append(ts.Columns, column{Name: name, Unit: unit, buffer: []byte{}, io: f})
 }
 
+var tablesAutoFlushOnce sync.Once
+
 func tableInit() {
 	err := fsutil.Remove(OD() + "table")
 	log.Log.PanicIfError(err)
@@ -110,12 +151,61 @@ func tableInit() {
 	Table.AddColumn("step", "")
 	Table.AddColumn("t", "s")
 	tableAdd(&NormMag)
-	go tablesAutoFlush()
+	tablesAutoFlushOnce.Do(func() { go tablesAutoFlush() })
+}
+
+// tableState is the part of the table that is swapped out while a sweep writes its own tables.
+type tableState struct {
+	quantities     []Quantity
+	columns        []column
+	data           map[string][]float64
+	autoSavePeriod float64
+	autoSaveStart  float64
+	step           int
+}
+
+func (ts *tableStruct) state() tableState {
+	ts.Mu.Lock()
+	defer ts.Mu.Unlock()
+	return tableState{ts.quantities, ts.Columns, ts.Data, ts.AutoSavePeriod, ts.AutoSaveStart, ts.Step}
+}
+
+func (ts *tableStruct) setState(s tableState) {
+	ts.Mu.Lock()
+	defer ts.Mu.Unlock()
+	ts.quantities, ts.Columns, ts.Data = s.quantities, s.columns, s.data
+	ts.AutoSavePeriod, ts.AutoSaveStart, ts.Step = s.autoSavePeriod, s.autoSaveStart, s.step
+}
+
+// restart starts an empty table with the same columns in the current output directory.
+func (ts *tableStruct) restart(s tableState) {
+	ts.setState(tableState{quantities: s.quantities, data: make(map[string][]float64), autoSavePeriod: s.autoSavePeriod, autoSaveStart: Time, step: -1})
+	if len(s.columns) == 0 {
+		return
+	}
+	zarr.InitZgroup(ts.name, OD())
+	ts.Mu.Lock()
+	defer ts.Mu.Unlock()
+	for _, c := range s.columns {
+		ts.AddColumn(c.Name, c.Unit)
+	}
+}
+
+// close flushes the table and closes its files.
+func (ts *tableStruct) close() {
+	ts.Flush()
+	ts.Mu.Lock()
+	defer ts.Mu.Unlock()
+	for _, c := range ts.Columns {
+		if err := c.io.Close(); err != nil {
+			log.Log.Err("Error closing table column %s: %v", c.Name, err)
+		}
+	}
 }
 
 func tablesAutoFlush() {
 	for {
-		Table.Flush()
+		flushTables()
 		time.Sleep(Table.FlushInterval)
 	}
 }
@@ -133,7 +223,11 @@ func tableAdd(q Quantity) {
 }
 
 func tableAddAs(q Quantity, name string) {
-	suffixes := []string{"x", "y", "z"}
+	tableAddColumns(q, name, "")
+}
+
+// tableAddColumns adds the average of q to the table, with the columns given by columnNames.
+func tableAddColumns(q Quantity, name, sep string) {
 	if Table.Step != -1 {
 		log.Log.Warn("You cannot add a new quantity to the table after the simulation has started. Ignoring.")
 	}
@@ -141,17 +235,14 @@ func tableAddAs(q Quantity, name string) {
 		tableInit()
 	}
 
-	if Table.Exists(q, name) {
+	names := columnNames(q, name, sep)
+	if Table.exists(names) {
 		log.Log.Warn("%s is already in the table. Ignoring.", name)
 		return
 	}
 	Table.quantities = append(Table.quantities, q)
-	if q.NComp() == 1 {
-		Table.AddColumn(name, unitOf(q))
-	} else {
-		for comp := 0; comp < q.NComp(); comp++ {
-			Table.AddColumn(name+suffixes[comp], unitOf(q))
-		}
+	for _, n := range names {
+		Table.AddColumn(n, unitOf(q))
 	}
 }
 
@@ -17,6 +17,7 @@ import (
 	"github.com/MathieuMoalic/amumax/src/engine"
 	"github.com/MathieuMoalic/amumax/src/flags"
 	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/notify"
 	"github.com/MathieuMoalic/amumax/src/queue"
 	"github.com/MathieuMoalic/amumax/src/script"
 	"github.com/MathieuMoalic/amumax/src/timer"
@@ -45,8 +46,21 @@ func Entrypoint(cmd *cobra.Command, args []string, flags *flags.Flags) {
 	}
 
 	engine.Insecure = flags.Insecure
+	if len(args) <= 1 {
+		engine.Notifier = newNotifier(flags)
+	}
+	engine.Hooks.AllowCommands = flags.Insecure
+	for _, spec := range flags.Hooks {
+		engine.AddHookSpec(spec)
+	}
 
 	defer engine.CleanExit() // flushes pending output, if any
+	defer func() {
+		if err := recover(); err != nil {
+			engine.FireError(fmt.Sprint(err))
+			panic(err)
+		}
+	}()
 
 	if flags.Vet {
 		engine.Vet()
@@ -58,12 +72,28 @@ func Entrypoint(cmd *cobra.Command, args []string, flags *flags.Flags) {
 	} else if len(args) == 1 {
 		runFileAndServe(args[0], flags)
 	} else if len(args) > 1 {
-		queue.RunQueue(args, flags)
+		queue.RunQueue(args, flags, newNotifier(flags)) // the queue notifies for its jobs
 	} else {
 		_ = cmd.Help()
 	}
 }
 
+// newNotifier creates the notifiers selected by the --notify-* flags, nil if there are none.
+func newNotifier(flags *flags.Flags) *notify.Notifiers {
+	notifier, err := notify.New(notify.Config{
+		Webhook:  flags.NotifyWebhook,
+		Template: flags.NotifyTemplate,
+		SMTP:     flags.NotifySMTP,
+		From:     flags.NotifyFrom,
+		To:       flags.NotifyTo,
+		On:       flags.NotifyOn,
+	})
+	if err != nil {
+		log.Log.ErrAndExit("Error: %v", err)
+	}
+	return notifier
+}
+
 type Release struct {
 	TagName string `json:"tag_name"`
 }
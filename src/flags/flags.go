@@ -26,6 +26,14 @@ type Flags struct {
 	Tunnel          string
 	Insecure        bool
 	NewEngine       bool
+	Hooks           []string
+
+	NotifyWebhook  string
+	NotifyTemplate string
+	NotifySMTP     string
+	NotifyFrom     string
+	NotifyTo       []string
+	NotifyOn       []string
 
 	WebUIDisabled     bool
 	WebUIAddress      string
@@ -50,6 +58,14 @@ func (flags *Flags) ParseFlags(rootCmd *cobra.Command) {
 	rootCmd.Flags().StringVarP(&flags.Tunnel, "tunnel", "t", "", "Tunnel the web interface through SSH using the given host from your ssh config, empty string disables tunneling")
 	rootCmd.Flags().BoolVar(&flags.Insecure, "insecure", false, "Allows to run shell commands")
 	rootCmd.Flags().BoolVarP(&flags.NewEngine, "new-engine", "n", false, "New engine, experimental")
+	rootCmd.Flags().StringArrayVar(&flags.Hooks, "hook", nil, "Run a command or webhook on an event, e.g. --hook 'on-finish=python analyse.py' or --hook 'on-save(m)=http://localhost:8000' (repeatable)")
+
+	rootCmd.Flags().StringVar(&flags.NotifyWebhook, "notify-webhook", "", "URL receiving a POST when a simulation finishes or fails, or when the queue is done")
+	rootCmd.Flags().StringVar(&flags.NotifyTemplate, "notify-template", "", "Go template (or file containing it) for the webhook body, e.g. '{\"text\": {{json .Text}}}'")
+	rootCmd.Flags().StringVar(&flags.NotifySMTP, "notify-smtp", "", "SMTP server (host:port) used to send notification emails, credentials are read from $AMUMAX_SMTP_USER and $AMUMAX_SMTP_PASSWORD")
+	rootCmd.Flags().StringVar(&flags.NotifyFrom, "notify-from", "", "Sender address of notification emails")
+	rootCmd.Flags().StringSliceVar(&flags.NotifyTo, "notify-to", nil, "Recipients of notification emails")
+	rootCmd.Flags().StringSliceVar(&flags.NotifyOn, "notify-on", nil, "Events to notify: finished, failed, queue-done (default all)")
 
 	rootCmd.Flags().BoolVar(&flags.WebUIDisabled, "webui-disable", false, "Whether to disable the web interface")
 	rootCmd.Flags().StringVar(&flags.WebUIAddress, "webui-addr", "localhost:35367", "Address (URI) to serve web GUI (e.g., 0.0.0.0:8080/proxy/worker1)")
@@ -65,3 +81,29 @@ func (flags *TemplateFlags) ParseFlags(templateCmd *cobra.Command) {
 	templateCmd.Flags().BoolVar(&flags.Flat, "flat", false, "Generate flat output without subdirectories")
 	templateCmd.Flags().BoolVar(&flags.Run, "run", false, "Run the generated script")
 }
+
+type RenderFlags struct {
+	Output    string
+	Layer     int
+	Component int
+	Colormap  string
+	Min       string
+	Max       string
+	Colorbar  bool
+	ArrowSize int
+	FPS       float64
+	Stride    int
+}
+
+func (flags *RenderFlags) ParseFlags(renderCmd *cobra.Command) {
+	renderCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "Output file, .gif or .png (APNG), defaults to <dataset>.gif")
+	renderCmd.Flags().IntVarP(&flags.Layer, "layer", "l", -1, "Layer (z index) to render, -1 averages all layers")
+	renderCmd.Flags().IntVarP(&flags.Component, "component", "c", -1, "Component to render, -1 renders vectors with HSL colors")
+	renderCmd.Flags().StringVar(&flags.Colormap, "cmap", "", "Colormap for scalar data: gray, viridis, magma, RdBu, twilight (add _r to reverse)")
+	renderCmd.Flags().StringVar(&flags.Min, "min", "", "Minimum of the color scale: a number, auto (per frame) or empty for the minimum over all frames")
+	renderCmd.Flags().StringVar(&flags.Max, "max", "", "Maximum of the color scale: a number, auto (per frame) or empty for the maximum over all frames")
+	renderCmd.Flags().BoolVar(&flags.Colorbar, "colorbar", false, "Add a colorbar to scalar data")
+	renderCmd.Flags().IntVar(&flags.ArrowSize, "arrows", 0, "Draw in-plane arrows every n cells for vector data, 0 disables them")
+	renderCmd.Flags().Float64Var(&flags.FPS, "fps", 10, "Frames per second")
+	renderCmd.Flags().IntVar(&flags.Stride, "stride", 1, "Render every n-th time step")
+}
@@ -138,6 +138,21 @@ func Open(p string) (io.ReadCloser, error) {
 	return os.Open(p)
 }
 
+// Glob returns the sorted names of the files matching the pattern, relative to the working directory
+// when the pattern is.
+func Glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(addWorkDir(pattern))
+	if err != nil || filepath.IsAbs(pattern) || wd == "" {
+		return matches, err
+	}
+	for i, m := range matches {
+		if matches[i], err = filepath.Rel(wd, m); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
 // WriteCloseFlusher represents a writer that can be flushed and closed.
 type WriteCloseFlusher interface {
 	io.WriteCloser
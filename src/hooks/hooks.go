@@ -0,0 +1,214 @@
+// Package hooks runs user-defined commands or webhooks when simulation events happen.
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MathieuMoalic/amumax/src/log"
+)
+
+type Event string
+
+const (
+	Start      Event = "on-start"
+	Save       Event = "on-save"
+	TableFlush Event = "on-table-flush"
+	Finish     Event = "on-finish"
+	Error      Event = "on-error"
+)
+
+var events = []Event{Start, Save, TableFlush, Finish, Error}
+
+// Payload is the JSON document describing the run, sent to every hook.
+type Payload struct {
+	Event    Event    `json:"event"`
+	OD       string   `json:"od"`
+	Step     int      `json:"step"`
+	Time     float64  `json:"time"`
+	Quantity string   `json:"quantity,omitempty"`
+	Files    []string `json:"files,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Hook is a command or a webhook attached to an event.
+type Hook struct {
+	Event    Event
+	Quantity string // on-save only: restricts the hook to one quantity, empty matches all
+	Target   string // shell command, or http(s):// URL for a webhook
+}
+
+// IsWebhook returns true if the target is an HTTP endpoint rather than a command.
+func (h Hook) IsWebhook() bool {
+	return strings.HasPrefix(h.Target, "http://") || strings.HasPrefix(h.Target, "https://")
+}
+
+func (h Hook) matches(p Payload) bool {
+	if h.Event != p.Event {
+		return false
+	}
+	return h.Quantity == "" || h.Quantity == p.Quantity
+}
+
+// NewHook parses an event like "on-finish" or "on-save(m)" and attaches it to target.
+func NewHook(event, target string) (Hook, error) {
+	ev, quantity, err := ParseEvent(event)
+	if err != nil {
+		return Hook{}, err
+	}
+	if strings.TrimSpace(target) == "" {
+		return Hook{}, fmt.Errorf("hook for %s has an empty target", event)
+	}
+	return Hook{Event: ev, Quantity: quantity, Target: target}, nil
+}
+
+// Parse parses a hook given on the command line as "event=target", e.g. "on-save(m)=python analyse.py".
+func Parse(spec string) (Hook, error) {
+	event, target, ok := strings.Cut(spec, "=")
+	if !ok {
+		return Hook{}, fmt.Errorf("invalid hook `%s`, expected event=target", spec)
+	}
+	return NewHook(strings.TrimSpace(event), strings.TrimSpace(target))
+}
+
+// ParseEvent splits "on-save(m)" into the event and the optional quantity name.
+func ParseEvent(s string) (Event, string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	name, quantity := s, ""
+	if i := strings.Index(s, "("); i != -1 {
+		if !strings.HasSuffix(s, ")") {
+			return "", "", fmt.Errorf("invalid hook event `%s`", s)
+		}
+		name, quantity = s[:i], strings.TrimSpace(s[i+1:len(s)-1])
+	}
+	for _, ev := range events {
+		if Event(name) != ev {
+			continue
+		}
+		if quantity != "" && ev != Save {
+			return "", "", fmt.Errorf("only %s accepts a quantity, got `%s`", Save, s)
+		}
+		return ev, quantity, nil
+	}
+	return "", "", fmt.Errorf("unknown hook event `%s`, expected one of %v", name, events)
+}
+
+// Registry holds the hooks of a run. The zero value is ready to use.
+type Registry struct {
+	AllowCommands bool          // commands are only run in insecure mode, webhooks are always allowed
+	Timeout       time.Duration // per hook, 0 means 30 seconds
+	mu            sync.Mutex
+	hooks         []Hook
+	wg            sync.WaitGroup
+}
+
+func (r *Registry) Add(h Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !h.IsWebhook() && !r.AllowCommands {
+		log.Log.Warn("Hook `%s` for %s ignored: insecure mode is disabled. To run commands, use the --insecure flag.", h.Target, h.Event)
+		return
+	}
+	r.hooks = append(r.hooks, h)
+}
+
+// Fire asynchronously invokes the hooks matching p. Matching hooks run one after the other.
+func (r *Registry) Fire(p Payload) {
+	r.mu.Lock()
+	var matched []Hook
+	for _, h := range r.hooks {
+		if h.matches(p) {
+			matched = append(matched, h)
+		}
+	}
+	r.mu.Unlock()
+	if len(matched) == 0 {
+		return
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		log.Log.Err("Error encoding hook payload: %v", err)
+		return
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for _, h := range matched {
+			if err := r.invoke(h, body); err != nil {
+				log.Log.Err("Hook %s `%s` failed: %v", h.Event, h.Target, err)
+			}
+		}
+	}()
+}
+
+// Wait blocks until all fired hooks have returned.
+func (r *Registry) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Registry) timeout() time.Duration {
+	if r.Timeout == 0 {
+		return 30 * time.Second
+	}
+	return r.Timeout
+}
+
+func (r *Registry) invoke(h Hook, body []byte) error {
+	if h.IsWebhook() {
+		return post(h.Target, body, r.timeout())
+	}
+	return run(h.Target, body, r.timeout())
+}
+
+// post sends the payload as the body of a JSON POST request.
+func post(url string, body []byte, timeout time.Duration) error {
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			log.Log.Err("Error closing response body: %v", cerr)
+		}
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// run executes the command through the shell, with the payload on stdin and in $AMUMAX_HOOK_PAYLOAD.
+func run(command string, body []byte, timeout time.Duration) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), "AMUMAX_HOOK_PAYLOAD="+string(body))
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if output.Len() > 0 {
+			log.Log.Info("%s", strings.TrimRight(output.String(), "\n"))
+		}
+		if err != nil {
+			return fmt.Errorf("%v\nOutput: %s", err, output.String())
+		}
+		return nil
+	case <-time.After(timeout):
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+}
@@ -0,0 +1,135 @@
+package hooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	cases := []struct {
+		input    string
+		event    Event
+		quantity string
+	}{
+		{"on-start", Start, ""},
+		{"on-save", Save, ""},
+		{"on-save(m)", Save, "m"},
+		{" On-Finish ", Finish, ""},
+		{"on-table-flush", TableFlush, ""},
+		{"on-error", Error, ""},
+	}
+	for _, c := range cases {
+		event, quantity, err := ParseEvent(c.input)
+		if err != nil {
+			t.Errorf("Unexpected error for input %q: %v", c.input, err)
+		}
+		if event != c.event || quantity != c.quantity {
+			t.Errorf("For input %q, expected (%q, %q), got (%q, %q)", c.input, c.event, c.quantity, event, quantity)
+		}
+	}
+}
+
+func TestParseEventInvalid(t *testing.T) {
+	for _, input := range []string{"on-bogus", "on-finish(m)", "on-save(m", ""} {
+		if _, _, err := ParseEvent(input); err == nil {
+			t.Errorf("Expected an error for input %q", input)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	h, err := Parse("on-save(m) = http://localhost:1234/hook")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if h.Event != Save || h.Quantity != "m" || h.Target != "http://localhost:1234/hook" || !h.IsWebhook() {
+		t.Errorf("Unexpected hook: %+v", h)
+	}
+	if _, err := Parse("on-finish"); err == nil {
+		t.Errorf("Expected an error for a hook without target")
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	received := make(chan Payload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON content type, got %q", r.Header.Get("Content-Type"))
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Error reading body: %v", err)
+		}
+		var p Payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Errorf("Error decoding payload %s: %v", body, err)
+		}
+		received <- p
+	}))
+	defer server.Close()
+
+	var r Registry
+	r.Add(Hook{Event: Save, Quantity: "m", Target: server.URL})
+	r.Add(Hook{Event: Finish, Target: server.URL})
+
+	r.Fire(Payload{Event: Save, OD: "out.zarr/", Step: 10, Time: 1e-9, Quantity: "B_ext", Files: []string{"out.zarr/B_ext"}})
+	r.Fire(Payload{Event: Save, OD: "out.zarr/", Step: 12, Time: 2e-9, Quantity: "m", Files: []string{"out.zarr/m"}})
+	r.Fire(Payload{Event: Finish, OD: "out.zarr/", Step: 20, Time: 3e-9})
+	r.Wait()
+	close(received)
+
+	var got []Payload
+	for p := range received {
+		got = append(got, p)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d: %+v", len(got), got)
+	}
+	for _, p := range got {
+		switch p.Event {
+		case Save:
+			if p.Quantity != "m" || p.Step != 12 || len(p.Files) != 1 || p.Files[0] != "out.zarr/m" {
+				t.Errorf("Unexpected save payload: %+v", p)
+			}
+		case Finish:
+			if p.Step != 20 || p.Time != 3e-9 || p.OD != "out.zarr/" {
+				t.Errorf("Unexpected finish payload: %+v", p)
+			}
+		default:
+			t.Errorf("Unexpected event: %+v", p)
+		}
+	}
+}
+
+func TestCommandRequiresInsecure(t *testing.T) {
+	var r Registry
+	r.Add(Hook{Event: Finish, Target: "true"})
+	if len(r.hooks) != 0 {
+		t.Errorf("Command hook registered without AllowCommands")
+	}
+}
+
+func TestCommandReceivesPayload(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "payload.json")
+	r := Registry{AllowCommands: true}
+	r.Add(Hook{Event: Start, Target: "cat > " + out})
+	r.Fire(Payload{Event: Start, OD: "sim.zarr/"})
+	r.Wait()
+
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Expected the command to write %s: %v", out, err)
+	}
+	var p Payload
+	if err := json.Unmarshal(content, &p); err != nil {
+		t.Fatalf("Error decoding payload %s: %v", content, err)
+	}
+	if p.Event != Start || p.OD != "sim.zarr/" {
+		t.Errorf("Unexpected payload: %+v", p)
+	}
+}
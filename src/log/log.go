@@ -19,6 +19,7 @@ type Logs struct {
 	logfile fsutil.WriteCloseFlusher // saves history of input commands +  output
 	debug   bool
 	path    string
+	onExit  []func(msg string) // called by ErrAndExit before exiting
 }
 
 func (l *Logs) AutoFlushToFile() {
@@ -120,9 +121,17 @@ func (l *Logs) PanicIfError(err error) {
 // ErrAndExit prints an error message in red, adds it to the log history, and exits with code 1
 func (l *Logs) ErrAndExit(msg string, args ...any) {
 	l.Err(msg, args...)
+	for _, f := range l.onExit {
+		f(fmt.Sprintf(msg, args...))
+	}
 	os.Exit(1)
 }
 
+// OnErrAndExit registers f to be called with the error message when ErrAndExit is about to exit
+func (l *Logs) OnErrAndExit(f func(msg string)) {
+	l.onExit = append(l.onExit, f)
+}
+
 // AssertMsg Panics with msg if test is false
 func (l *Logs) AssertMsg(test bool, msg any) {
 	if !test {
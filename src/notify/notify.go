@@ -0,0 +1,281 @@
+// Package notify sends notifications when simulations finish or fail, through webhooks or email.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/MathieuMoalic/amumax/src/log"
+)
+
+type Event string
+
+const (
+	Finished  Event = "finished"   // a simulation ended cleanly
+	Failed    Event = "failed"     // a simulation exited with an error
+	QueueDone Event = "queue-done" // the queue ran all its jobs or was terminated
+)
+
+var events = []Event{Finished, Failed, QueueDone}
+
+// Message describes the run that triggered the notification.
+type Message struct {
+	Event     Event
+	Host      string
+	Job       string // input file
+	OD        string
+	Runtime   time.Duration
+	LogTail   string
+	Error     string
+	NumOK     int // queue-done only
+	NumFailed int // queue-done only
+}
+
+func NewMessage(event Event, job, od string, runtime time.Duration) Message {
+	host, _ := os.Hostname()
+	return Message{Event: event, Host: host, Job: job, OD: od, Runtime: runtime.Round(time.Second)}
+}
+
+// Subject is a one-line summary, used as email subject.
+func (m Message) Subject() string {
+	switch m.Event {
+	case QueueDone:
+		return fmt.Sprintf("amumax queue on %s done: %d OK, %d failed (%v)", m.Host, m.NumOK, m.NumFailed, m.Runtime)
+	default:
+		return fmt.Sprintf("amumax: %s %s on %s after %v", m.Job, m.Event, m.Host, m.Runtime)
+	}
+}
+
+// Text is the full human-readable message.
+func (m Message) Text() string {
+	var b strings.Builder
+	b.WriteString(m.Subject() + "\n")
+	if m.OD != "" {
+		fmt.Fprintf(&b, "Output: %s\n", m.OD)
+	}
+	if m.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", m.Error)
+	}
+	if m.LogTail != "" {
+		fmt.Fprintf(&b, "\n%s\n", m.LogTail)
+	}
+	return b.String()
+}
+
+type Notifier interface {
+	Notify(m Message) error
+}
+
+// DefaultTemplate is the webhook body when no template is given.
+const DefaultTemplate = `{"event": {{json .Event}}, "host": {{json .Host}}, "job": {{json .Job}}, "od": {{json .OD}}, ` +
+	`"runtime": {{json .Runtime.Seconds}}, "error": {{json .Error}}, "ok": {{.NumOK}}, "failed": {{.NumFailed}}, ` +
+	`"log_tail": {{json .LogTail}}, "text": {{json .Text}}}`
+
+// Webhook POSTs the rendered template to URL, e.g. `{"text": {{json .Text}}}` for Slack or Matrix hooks,
+// or `{{.Text}}` for ntfy.
+type Webhook struct {
+	URL  string
+	Body *template.Template
+}
+
+func NewWebhook(url, body string) (*Webhook, error) {
+	if body == "" {
+		body = DefaultTemplate
+	}
+	t, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %v", err)
+	}
+	return &Webhook{URL: url, Body: t}, nil
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (w *Webhook) Notify(m Message) error {
+	var body bytes.Buffer
+	if err := w.Body.Execute(&body, m); err != nil {
+		return err
+	}
+	contentType := "text/plain"
+	if json.Valid(body.Bytes()) {
+		contentType = "application/json"
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(w.URL, contentType, &body)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			log.Log.Err("Error closing response body: %v", cerr)
+		}
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTP sends the message as a plain text email. Authentication is used when Username is set.
+type SMTP struct {
+	Addr     string // host:port
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+func (s *SMTP) Notify(m Message) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", m.Subject())
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(m.Text(), "\n", "\r\n"))
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, _ := strings.Cut(s.Addr, ":")
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, s.To, msg.Bytes())
+}
+
+// Notifiers dispatches messages for the selected events. A nil *Notifiers sends nothing.
+type Notifiers struct {
+	list   []Notifier
+	events map[Event]bool // empty means every event
+}
+
+// Config selects the notifiers, from the --notify-* flags.
+type Config struct {
+	Webhook  string   // URL, empty for no webhook
+	Template string   // webhook body, or a file containing it
+	SMTP     string   // host:port, empty for no email
+	From     string   // sender of the emails
+	To       []string // recipients of the emails
+	On       []string // events to notify, empty means every event
+}
+
+// New creates the notifiers enabled by c, nil if there are none.
+func New(c Config) (*Notifiers, error) {
+	n := &Notifiers{events: make(map[Event]bool)}
+	if c.Webhook != "" {
+		body := c.Template
+		if content, err := os.ReadFile(body); err == nil {
+			body = string(content)
+		}
+		w, err := NewWebhook(c.Webhook, body)
+		if err != nil {
+			return nil, err
+		}
+		n.list = append(n.list, w)
+	}
+	if c.SMTP != "" {
+		if c.From == "" || len(c.To) == 0 {
+			return nil, fmt.Errorf("--notify-smtp requires --notify-from and --notify-to")
+		}
+		n.list = append(n.list, &SMTP{
+			Addr:     c.SMTP,
+			From:     c.From,
+			To:       c.To,
+			Username: os.Getenv("AMUMAX_SMTP_USER"),
+			Password: os.Getenv("AMUMAX_SMTP_PASSWORD"),
+		})
+	}
+	if len(n.list) == 0 {
+		return nil, nil
+	}
+	for _, e := range c.On {
+		ev := Event(strings.TrimSpace(e))
+		if !isEvent(ev) {
+			return nil, fmt.Errorf("unknown notification event `%s`, expected one of %v", e, events)
+		}
+		n.events[ev] = true
+	}
+	return n, nil
+}
+
+func isEvent(e Event) bool {
+	for _, ev := range events {
+		if ev == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Add registers a notifier.
+func (n *Notifiers) Add(nt Notifier) {
+	n.list = append(n.list, nt)
+}
+
+func (n *Notifiers) wants(e Event) bool {
+	return len(n.events) == 0 || n.events[e]
+}
+
+// Send delivers m to every notifier, logging failures. It blocks until all deliveries returned.
+func (n *Notifiers) Send(m Message) {
+	if n == nil || !n.wants(m.Event) {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, nt := range n.list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := nt.Notify(m); err != nil {
+				log.Log.Err("Error sending %s notification: %v", m.Event, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ansiEscape matches the color codes of the log, which mean nothing in an email or a webhook body.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// Tail returns the last n lines of s, without ANSI escape codes.
+func Tail(s string, n int) string {
+	s = ansiEscape.ReplaceAllString(s, "")
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TailBuffer is an io.Writer keeping only the last lines written to it.
+type TailBuffer struct {
+	Lines int
+	mu    sync.Mutex
+	buf   []byte
+}
+
+func (t *TailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > 64*1024 {
+		t.buf = []byte(Tail(string(t.buf), t.Lines) + "\n")
+	}
+	return len(p), nil
+}
+
+func (t *TailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Tail(string(t.buf), t.Lines)
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, received chan<- []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Error reading body: %v", err)
+		}
+		received <- body
+	}))
+}
+
+func TestWebhookDefaultTemplate(t *testing.T) {
+	received := make(chan []byte, 1)
+	server := newTestServer(t, received)
+	defer server.Close()
+
+	w, err := NewWebhook(server.URL, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	m := Message{Event: Failed, Host: "gpu1", Job: "sim.mx3", OD: "sim.zarr/", Runtime: 90 * time.Second,
+		Error: "NaN in \"m\"", LogTail: "line 1\nline 2"}
+	if err := w.Notify(m); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(<-received, &body); err != nil {
+		t.Fatalf("Default template is not valid JSON: %v", err)
+	}
+	if body["event"] != "failed" || body["job"] != "sim.mx3" || body["runtime"] != 90.0 || body["error"] != "NaN in \"m\"" {
+		t.Errorf("Unexpected body: %v", body)
+	}
+	if body["log_tail"] != "line 1\nline 2" {
+		t.Errorf("Unexpected log tail: %q", body["log_tail"])
+	}
+}
+
+func TestWebhookCustomTemplate(t *testing.T) {
+	received := make(chan []byte, 1)
+	server := newTestServer(t, received)
+	defer server.Close()
+
+	w, err := NewWebhook(server.URL, `{{.Job}} {{.Event}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := w.Notify(Message{Event: Finished, Job: "sim.mx3"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := string(<-received); got != "sim.mx3 finished" {
+		t.Errorf("Unexpected body: %q", got)
+	}
+}
+
+func TestNotifiersFilterEvents(t *testing.T) {
+	received := make(chan []byte, 3)
+	server := newTestServer(t, received)
+	defer server.Close()
+
+	n, err := New(Config{Webhook: server.URL, Template: `{{.Event}}`, On: []string{"failed"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n.Send(Message{Event: Finished})
+	n.Send(Message{Event: Failed})
+	n.Send(Message{Event: QueueDone})
+	close(received)
+	var got []string
+	for b := range received {
+		got = append(got, string(b))
+	}
+	if len(got) != 1 || got[0] != "failed" {
+		t.Errorf("Expected only the failed notification, got %v", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	n, err := New(Config{})
+	if err != nil || n != nil {
+		t.Errorf("Expected no notifiers without settings, got %v, %v", n, err)
+	}
+	if _, err := New(Config{SMTP: "localhost:25"}); err == nil {
+		t.Errorf("Expected an error for SMTP without sender and recipients")
+	}
+	if _, err := New(Config{Webhook: "http://localhost", On: []string{"done"}}); err == nil {
+		t.Errorf("Expected an error for an unknown event")
+	}
+	if _, err := New(Config{Webhook: "http://localhost", Template: "{{.Job"}); err == nil {
+		t.Errorf("Expected an error for an invalid template")
+	}
+	var nilNotifiers *Notifiers
+	nilNotifiers.Send(Message{Event: Finished}) // must not panic
+}
+
+func TestTailBuffer(t *testing.T) {
+	tb := &TailBuffer{Lines: 3}
+	for i := 0; i < 10000; i++ {
+		_, _ = tb.Write([]byte("some log line\n"))
+	}
+	_, _ = tb.Write([]byte("a\nb\nc\n"))
+	if got := tb.String(); got != "a\nb\nc" {
+		t.Errorf("Unexpected tail: %q", got)
+	}
+	if got := Tail("1\n2\n", 5); got != "1\n2" {
+		t.Errorf("Unexpected tail: %q", got)
+	}
+
+	// the colors of the log do not reach the emails and webhooks
+	colored := &TailBuffer{Lines: 2}
+	_, _ = colored.Write([]byte("\x1b[0;32m// step 1\x1b[0m\n\x1b[1;31mError: NaN\x1b[0m\n"))
+	if got := colored.String(); got != "// step 1\nError: NaN" {
+		t.Errorf("Unexpected tail: %q", got)
+	}
+}
+
+func TestMessageText(t *testing.T) {
+	m := Message{Event: QueueDone, Host: "gpu1", NumOK: 3, NumFailed: 1, Runtime: time.Hour}
+	if !strings.Contains(m.Text(), "3 OK, 1 failed") {
+		t.Errorf("Unexpected text: %q", m.Text())
+	}
+}
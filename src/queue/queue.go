@@ -8,22 +8,32 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/MathieuMoalic/amumax/src/api"
 	"github.com/MathieuMoalic/amumax/src/cuda/cu"
 	"github.com/MathieuMoalic/amumax/src/flags"
 	"github.com/MathieuMoalic/amumax/src/log"
+	"github.com/MathieuMoalic/amumax/src/notify"
 	"github.com/MathieuMoalic/amumax/src/url"
 )
 
 var (
 	exitStatus       atom = 0
 	numOK, numFailed atom = 0, 0
+	notifier         *notify.Notifiers
 )
 
-func RunQueue(files []string, flags *flags.Flags) {
+// RunQueue runs the files as separate jobs and sends the notifications of n, which may be nil.
+func RunQueue(files []string, flags *flags.Flags, n *notify.Notifiers) {
+	start := time.Now()
+	notifier = n
+	go notifyOnSignal(start)
 	s := NewStateTab(files)
 	host, port, path, err := url.ParseAddrPath(flags.WebUIQueueAddress)
 	log.Log.PanicIfError(err)
@@ -37,9 +47,27 @@ func RunQueue(files []string, flags *flags.Flags) {
 	go s.ListenAndServe(addr)
 	s.Run(flags)
 	log.Log.Command(fmt.Sprintf("%d OK; %d Failed", numOK.get(), numFailed.get()))
+	notifyQueueDone(start, "")
 	os.Exit(int(exitStatus))
 }
 
+func notifyQueueDone(start time.Time, errMsg string) {
+	m := notify.NewMessage(notify.QueueDone, "", "", time.Since(start))
+	m.NumOK, m.NumFailed = numOK.get(), numFailed.get()
+	m.Error = errMsg
+	notifier.Send(m)
+}
+
+// notifyOnSignal sends the queue-done notification when the scheduler is terminated, e.g. by SLURM.
+func notifyOnSignal(start time.Time) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	s := <-sig
+	log.Log.Warn("Queue terminated by %v", s)
+	notifyQueueDone(start, fmt.Sprintf("terminated by %v", s))
+	os.Exit(1)
+}
+
 // StateTab holds the queue state (list of jobs + statuses).
 // All operations are atomic.
 type stateTab struct {
@@ -155,6 +183,9 @@ func run(inFile string, gpu int, flags *flags.Flags) {
 	if flags.NewEngine {
 		cmd = append(cmd, "--new-parser")
 	}
+	for _, h := range flags.Hooks {
+		cmd = append(cmd, "--hook", h)
+	}
 	if flags.WebUIDisabled {
 		cmd = append(cmd, "--webui-disable")
 	}
@@ -172,15 +203,28 @@ func run(inFile string, gpu int, flags *flags.Flags) {
 		cmdString += c + " "
 	}
 	log.Log.Command(fmt.Sprintf("Running %s", cmdString))
-	err := exec.Command(cmd[0], cmd[1:]...).Run()
+	start := time.Now()
+	output := &notify.TailBuffer{Lines: 20}
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Stdout = output
+	c.Stderr = output
+	err := c.Run()
+	od := strings.TrimSuffix(inFile, ".mx3") + ".zarr/"
 	if err != nil {
 		log.Log.Command(fmt.Sprintf("FAILED %s on GPU %d: %v", inFile, gpu, err))
 		exitStatus = 1
 		numFailed.inc()
+		m := notify.NewMessage(notify.Failed, inFile, od, time.Since(start))
+		m.Error = err.Error()
+		m.LogTail = output.String()
+		notifier.Send(m)
 		return
 	}
 	log.Log.Command(fmt.Sprintf("DONE %s on GPU %d", inFile, gpu))
 	numOK.inc()
+	m := notify.NewMessage(notify.Finished, inFile, od, time.Since(start))
+	m.LogTail = output.String()
+	notifier.Send(m)
 }
 
 func initGPUs(nGpu int) chan int {
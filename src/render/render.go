@@ -0,0 +1,143 @@
+// Package render turns a dataset saved in a .zarr output into an animated GIF or APNG.
+package render
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+	"github.com/MathieuMoalic/amumax/src/draw"
+	"github.com/MathieuMoalic/amumax/src/flags"
+	"github.com/MathieuMoalic/amumax/src/zarr"
+)
+
+// Render renders every time step of the dataset in dir, e.g. sim.zarr/m, into one animation.
+func Render(dir string, f *flags.RenderFlags) error {
+	dir = strings.TrimSuffix(dir, "/")
+	ds, err := zarr.OpenDataset(dir)
+	if err != nil {
+		return fmt.Errorf("error opening dataset: %v", err)
+	}
+	if ds.Steps() == 0 {
+		return fmt.Errorf("%s has no saved time step", dir)
+	}
+	if f.Layer >= ds.Size()[2] {
+		return fmt.Errorf("layer %d out of range, the dataset has %d layers", f.Layer, ds.Size()[2])
+	}
+	if f.Component >= ds.NComp() {
+		return fmt.Errorf("component %d out of range, the dataset has %d components", f.Component, ds.NComp())
+	}
+	if f.Stride < 1 {
+		return fmt.Errorf("stride must be at least 1, got %d", f.Stride)
+	}
+	if f.FPS <= 0 {
+		return fmt.Errorf("fps must be positive, got %g", f.FPS)
+	}
+	output := f.Output
+	if output == "" {
+		output = filepath.Base(dir) + ".gif"
+	}
+
+	opt := draw.Options{Min: f.Min, Max: f.Max, ArrowSize: f.ArrowSize, Colorbar: f.Colorbar, ShowTime: true}
+	opt.Title = filepath.Base(dir)
+	if f.Component >= 0 && ds.NComp() > 1 {
+		opt.Title += "xyz"[f.Component : f.Component+1]
+	}
+	if f.Colormap != "" {
+		cmap, err := draw.ColorMap(f.Colormap)
+		if err != nil {
+			return err
+		}
+		opt.ColorMap = &draw.ColorMapSpec{Cmap: cmap, Ccomp: -1}
+	}
+
+	var steps []int
+	for t := 0; t < ds.Steps(); t += f.Stride {
+		steps = append(steps, t)
+	}
+	scalar := ds.NComp() == 1 || f.Component >= 0
+	// a fixed scale over the whole movie, so that colors are comparable between frames
+	if scalar && (opt.Min == "" || opt.Max == "") {
+		min, max, err := extrema(ds, steps, f)
+		if err != nil {
+			return err
+		}
+		if opt.Min == "" {
+			opt.Min = strconv.FormatFloat(float64(min), 'g', -1, 32)
+		}
+		if opt.Max == "" {
+			opt.Max = strconv.FormatFloat(float64(max), 'g', -1, 32)
+		}
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	delay := time.Duration(float64(time.Second) / f.FPS)
+	movie, err := draw.NewMovieWriter(out, output, len(steps), delay)
+	if err != nil {
+		return err
+	}
+	for i, t := range steps {
+		s, err := frame(ds, t, f)
+		if err != nil {
+			return err
+		}
+		opt.Time = ds.Time(t)
+		if err := movie.WriteFrame(draw.Image(s, opt)); err != nil {
+			return err
+		}
+		fmt.Printf("\rRendering %s: frame %d/%d", output, i+1, len(steps))
+	}
+	fmt.Println()
+	if err := movie.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// frame reads time step t, restricted to the selected layer and component.
+func frame(ds *zarr.Dataset, t int, f *flags.RenderFlags) (*data.Slice, error) {
+	s, err := ds.ReadStep(t)
+	if err != nil {
+		return nil, err
+	}
+	if f.Layer >= 0 {
+		size := s.Size()
+		layer := data.NewSlice(s.NComp(), [3]int{size[0], size[1], 1})
+		for c := 0; c < s.NComp(); c++ {
+			copy(layer.Host()[c], s.Host()[c][f.Layer*size[0]*size[1]:(f.Layer+1)*size[0]*size[1]])
+		}
+		s = layer
+	}
+	if f.Component >= 0 && s.NComp() > 1 {
+		s = s.Comp(f.Component)
+	}
+	return s, nil
+}
+
+func extrema(ds *zarr.Dataset, steps []int, f *flags.RenderFlags) (min, max float32, err error) {
+	min, max = float32(math.Inf(1)), float32(math.Inf(-1))
+	for _, t := range steps {
+		s, err := frame(ds, t, f)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, v := range s.Host()[0] {
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+	}
+	return min, max, nil
+}
@@ -0,0 +1,70 @@
+package script
+
+import (
+	"go/ast"
+	"reflect"
+)
+
+// function literal without return values, e.g.:
+//
+//	func(B float64) { B_ext = vector(0, 0, B); Run(1e-9) }
+//
+// It can be passed to native functions that take a func with matching arguments.
+type funcLit struct {
+	params []LValue
+	body   *BlockStmt
+	typ    reflect.Type
+}
+
+var paramTypes = map[string]reflect.Type{
+	"float64": float64t,
+	"int":     intt,
+	"string":  stringt,
+	"bool":    boolt,
+}
+
+func (w *World) compileFuncLit(n *ast.FuncLit) Expr {
+	if n.Type.Results != nil && len(n.Type.Results.List) != 0 {
+		panic(err(n.Pos(), "function literals can not return values"))
+	}
+	w.EnterScope()
+	defer w.ExitScope()
+
+	lit := &funcLit{}
+	var in []reflect.Type
+	for _, field := range n.Type.Params.List {
+		ident, ok := field.Type.(*ast.Ident)
+		if !ok || paramTypes[ident.Name] == nil {
+			panic(err(field.Pos(), "parameter type not allowed:", Format(field.Type)))
+		}
+		t := paramTypes[ident.Name]
+		for _, name := range field.Names {
+			param := &reflectLvalue{reflect.New(t).Elem()}
+			if !w.safeDeclare(name.Name, param) {
+				panic(err(name.Pos(), "already defined: "+name.Name))
+			}
+			lit.params = append(lit.params, param)
+			in = append(in, t)
+		}
+	}
+	lit.body = w.compileBlockStmtNoScopeST(n.Body)
+	lit.typ = reflect.FuncOf(in, nil, false)
+	return lit
+}
+
+func (f *funcLit) Eval() any {
+	return reflect.MakeFunc(f.typ, func(args []reflect.Value) []reflect.Value {
+		// like loop bodies, assignments in functions are not recorded in the metadata
+		loopNestingCount++
+		defer func() { loopNestingCount-- }()
+		for i, a := range args {
+			f.params[i].SetValue(a.Interface())
+		}
+		f.body.Eval()
+		return nil
+	}).Interface()
+}
+
+func (f *funcLit) Type() reflect.Type { return f.typ }
+func (f *funcLit) Child() []Expr      { return []Expr{f.body} }
+func (f *funcLit) Fix() Expr          { return f }
@@ -96,6 +96,37 @@ func TestLoop(t *testing.T) {
 	}
 }
 
+func TestFuncLit(t *testing.T) {
+	w := NewWorld()
+	var calls []float64
+	w.Func("each", func(f func(x float64), n int) {
+		for i := 0; i < n; i++ {
+			f(float64(i) / 2)
+		}
+	})
+	sum := 0.0
+	w.Var("sum", &sum)
+	w.Func("record", func(x float64) { calls = append(calls, x) })
+	w.MustExec(`each(func(x float64) { sum = sum + x; record(x) }, 3)`)
+	if sum != 1.5 || !reflect.DeepEqual(calls, []float64{0, 0.5, 1}) {
+		t.Error("got", sum, calls)
+	}
+
+	// the parameter is scoped to the literal
+	if _, err := w.Compile("x"); err == nil {
+		t.Error("x should not be defined outside the function literal")
+	}
+	for _, src := range []string{
+		`each(func(x float64) float64 { return x }, 1)`,
+		`each(func(x []float64) {}, 1)`,
+		`each(func(s string) {}, 1)`,
+	} {
+		if _, err := w.Compile(src); err == nil {
+			t.Error(src, "should not compile")
+		}
+	}
+}
+
 type test struct{}
 
 func (t *test) A() int { return 41 }
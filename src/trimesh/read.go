@@ -0,0 +1,144 @@
+package trimesh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ReadSTL reads a binary or ASCII STL file.
+func ReadSTL(r io.Reader) (*Mesh, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// ASCII files also start with "solid", the size of a binary file is given by its triangle count
+	if len(b) >= 84 {
+		n := int(binary.LittleEndian.Uint32(b[80:84]))
+		if len(b) == 84+50*n {
+			return readBinarySTL(b[84:], n), nil
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("solid")) {
+		return readASCIISTL(b)
+	}
+	return nil, fmt.Errorf("trimesh: not a valid STL file")
+}
+
+func readBinarySTL(b []byte, n int) *Mesh {
+	triangles := make([][3][3]float64, n)
+	for i := range triangles {
+		rec := b[50*i:]
+		// the normal (first 12 bytes) is not used, the orientation does not matter for the inside test
+		for v := 0; v < 3; v++ {
+			for k := 0; k < 3; k++ {
+				bits := binary.LittleEndian.Uint32(rec[12+12*v+4*k:])
+				triangles[i][v][k] = float64(math.Float32frombits(bits))
+			}
+		}
+	}
+	return New(triangles)
+}
+
+func readASCIISTL(b []byte) (*Mesh, error) {
+	var triangles [][3][3]float64
+	var t [3][3]float64
+	nv := 0
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "vertex":
+			if len(fields) != 4 || nv >= 3 {
+				return nil, fmt.Errorf("trimesh: invalid vertex on line %d", line)
+			}
+			v, err := parseFloats(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("trimesh: line %d: %v", line, err)
+			}
+			copy(t[nv][:], v)
+			nv++
+		case "endfacet":
+			if nv != 3 {
+				return nil, fmt.Errorf("trimesh: facet with %d vertices on line %d", nv, line)
+			}
+			triangles = append(triangles, t)
+			nv = 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return New(triangles), nil
+}
+
+// ReadOBJ reads the vertices and faces of a Wavefront OBJ file. Polygons are split into triangles.
+func ReadOBJ(r io.Reader) (*Mesh, error) {
+	var vertices [][3]float64
+	var triangles [][3][3]float64
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("trimesh: invalid vertex on line %d", line)
+			}
+			v, err := parseFloats(fields[1:4])
+			if err != nil {
+				return nil, fmt.Errorf("trimesh: line %d: %v", line, err)
+			}
+			vertices = append(vertices, [3]float64{v[0], v[1], v[2]})
+		case "f":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("trimesh: face with less than 3 vertices on line %d", line)
+			}
+			face := make([][3]float64, 0, len(fields)-1)
+			for _, f := range fields[1:] {
+				// v, v/vt, v//vn or v/vt/vn, negative indices count from the last vertex
+				i, err := strconv.Atoi(strings.SplitN(f, "/", 2)[0])
+				if err == nil && i < 0 {
+					i += len(vertices) + 1
+				}
+				if err != nil || i < 1 || i > len(vertices) {
+					return nil, fmt.Errorf("trimesh: invalid vertex index `%s` on line %d", f, line)
+				}
+				face = append(face, vertices[i-1])
+			}
+			for k := 1; k+1 < len(face); k++ {
+				triangles = append(triangles, [3][3]float64{face[0], face[k], face[k+1]})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return New(triangles), nil
+}
+
+func parseFloats(fields []string) ([]float64, error) {
+	out := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,247 @@
+// Package trimesh reads closed triangle meshes (STL, OBJ) and tests whether points lie inside them.
+package trimesh
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Mesh is a triangle mesh. Points are inside if a ray along +x from them crosses the surface an odd
+// number of times, so the mesh should be closed.
+type Mesh struct {
+	Triangles [][3][3]float64 // [triangle][vertex][x, y, z]
+
+	grid  *yzGrid
+	mu    sync.Mutex
+	cache map[[2]float64][]float64 // sorted x of the crossings of the line (y, z)
+}
+
+// maximum number of cached lines, the cache is cleared when it is full
+const maxCachedLines = 1 << 16
+
+// New returns the mesh of the given triangles.
+func New(triangles [][3][3]float64) *Mesh {
+	return &Mesh{Triangles: triangles}
+}
+
+// Scale multiplies all coordinates by s, e.g. to convert from file units to meters.
+func (m *Mesh) Scale(s float64) {
+	for i := range m.Triangles {
+		for j := range m.Triangles[i] {
+			for k := range m.Triangles[i][j] {
+				m.Triangles[i][j][k] *= s
+			}
+		}
+	}
+	m.reset()
+}
+
+// Translate moves the mesh by d.
+func (m *Mesh) Translate(d [3]float64) {
+	for i := range m.Triangles {
+		for j := range m.Triangles[i] {
+			for k := range m.Triangles[i][j] {
+				m.Triangles[i][j][k] += d[k]
+			}
+		}
+	}
+	m.reset()
+}
+
+func (m *Mesh) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.grid, m.cache = nil, nil
+}
+
+// Bounds returns the corners of the bounding box.
+func (m *Mesh) Bounds() (lo, hi [3]float64) {
+	for k := range lo {
+		lo[k], hi[k] = math.Inf(1), math.Inf(-1)
+	}
+	for _, t := range m.Triangles {
+		for _, v := range t {
+			for k := range v {
+				lo[k] = math.Min(lo[k], v[k])
+				hi[k] = math.Max(hi[k], v[k])
+			}
+		}
+	}
+	return lo, hi
+}
+
+// Center returns the center of the bounding box.
+func (m *Mesh) Center() [3]float64 {
+	lo, hi := m.Bounds()
+	return [3]float64{(lo[0] + hi[0]) / 2, (lo[1] + hi[1]) / 2, (lo[2] + hi[2]) / 2}
+}
+
+// OpenEdges returns the number of edges that do not belong to exactly two triangles,
+// 0 for a closed mesh. Vertices are matched by their exact coordinates.
+func (m *Mesh) OpenEdges() int {
+	type edge [2][3]float64
+	count := make(map[edge]int)
+	for _, t := range m.Triangles {
+		for i := range t {
+			a, b := t[i], t[(i+1)%3]
+			if less(b, a) {
+				a, b = b, a
+			}
+			count[edge{a, b}]++
+		}
+	}
+	open := 0
+	for _, n := range count {
+		if n != 2 {
+			open++
+		}
+	}
+	return open
+}
+
+func less(a, b [3]float64) bool {
+	for k := range a {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return false
+}
+
+// Inside reports whether (x, y, z) is inside the mesh. It is safe for concurrent use.
+func (m *Mesh) Inside(x, y, z float64) bool {
+	xs := m.crossings(y, z)
+	// number of crossings beyond x
+	n := len(xs) - sort.SearchFloat64s(xs, math.Nextafter(x, math.Inf(1)))
+	return n%2 == 1
+}
+
+// crossings returns the sorted x coordinates where the line (y, z) along x crosses the surface.
+func (m *Mesh) crossings(y, z float64) []float64 {
+	key := [2]float64{y, z}
+	m.mu.Lock()
+	if m.grid == nil {
+		m.grid = newYZGrid(m.Triangles)
+		m.cache = make(map[[2]float64][]float64)
+	}
+	if xs, ok := m.cache[key]; ok {
+		m.mu.Unlock()
+		return xs
+	}
+	grid := m.grid
+	m.mu.Unlock()
+
+	var xs []float64
+	for _, i := range grid.candidates(y, z) {
+		if x, ok := crossX(&m.Triangles[i], y, z); ok {
+			xs = append(xs, x)
+		}
+	}
+	sort.Float64s(xs)
+
+	m.mu.Lock()
+	if len(m.cache) >= maxCachedLines {
+		m.cache = make(map[[2]float64][]float64)
+	}
+	m.cache[key] = xs
+	m.mu.Unlock()
+	return xs
+}
+
+// crossX returns where the line (y, z) along x crosses the triangle, if it does.
+// Points on an edge shared by two triangles are attributed to exactly one of them,
+// so that a line through an edge of a closed mesh is not counted twice.
+func crossX(t *[3][3]float64, y, z float64) (float64, bool) {
+	a, b, c := t[0], t[1], t[2]
+	// signed area in the yz plane, made positive by swapping two vertices
+	area := (b[1]-a[1])*(c[2]-a[2]) - (b[2]-a[2])*(c[1]-a[1])
+	if area == 0 {
+		return 0, false // parallel to the line
+	}
+	if area < 0 {
+		b, c = c, b
+		area = -area
+	}
+	w0, in0 := edgeWeight(b, c, y, z)
+	w1, in1 := edgeWeight(c, a, y, z)
+	w2, in2 := edgeWeight(a, b, y, z)
+	if !in0 || !in1 || !in2 {
+		return 0, false
+	}
+	return (w0*a[0] + w1*b[0] + w2*c[0]) / area, true
+}
+
+// edgeWeight returns the barycentric weight (times twice the area) of the vertex opposite to the edge
+// p→q for the point (y, z), and whether the point is on the inner side of the edge. Points on the edge
+// itself are inside for edges going down, or going left on a horizontal edge (the top-left rule).
+func edgeWeight(p, q [3]float64, y, z float64) (float64, bool) {
+	dy, dz := q[1]-p[1], q[2]-p[2]
+	w := dy*(z-p[2]) - dz*(y-p[1])
+	if w != 0 {
+		return w, w > 0
+	}
+	return 0, dz < 0 || (dz == 0 && dy < 0)
+}
+
+// yzGrid bins triangles by their bounding box in the yz plane.
+type yzGrid struct {
+	y0, z0, dy, dz float64
+	ny, nz         int
+	cells          [][]int
+}
+
+func newYZGrid(triangles [][3][3]float64) *yzGrid {
+	g := &yzGrid{ny: 1, nz: 1}
+	if len(triangles) == 0 {
+		g.cells = make([][]int, 1)
+		g.dy, g.dz = 1, 1
+		return g
+	}
+	lo := [2]float64{math.Inf(1), math.Inf(1)}
+	hi := [2]float64{math.Inf(-1), math.Inf(-1)}
+	for _, t := range triangles {
+		for _, v := range t {
+			lo[0], hi[0] = math.Min(lo[0], v[1]), math.Max(hi[0], v[1])
+			lo[1], hi[1] = math.Min(lo[1], v[2]), math.Max(hi[1], v[2])
+		}
+	}
+	n := max(1, int(math.Sqrt(float64(len(triangles)))))
+	g.ny, g.nz = n, n
+	g.y0, g.z0 = lo[0], lo[1]
+	g.dy = math.Max(hi[0]-lo[0], 1e-300) / float64(n)
+	g.dz = math.Max(hi[1]-lo[1], 1e-300) / float64(n)
+	g.cells = make([][]int, n*n)
+	for i, t := range triangles {
+		tlo := [2]float64{math.Min(t[0][1], math.Min(t[1][1], t[2][1])), math.Min(t[0][2], math.Min(t[1][2], t[2][2]))}
+		thi := [2]float64{math.Max(t[0][1], math.Max(t[1][1], t[2][1])), math.Max(t[0][2], math.Max(t[1][2], t[2][2]))}
+		iy0, iz0 := g.index(tlo[0], tlo[1])
+		iy1, iz1 := g.index(thi[0], thi[1])
+		for iz := iz0; iz <= iz1; iz++ {
+			for iy := iy0; iy <= iy1; iy++ {
+				g.cells[iz*g.ny+iy] = append(g.cells[iz*g.ny+iy], i)
+			}
+		}
+	}
+	return g
+}
+
+func (g *yzGrid) index(y, z float64) (int, int) {
+	iy := int((y - g.y0) / g.dy)
+	iz := int((z - g.z0) / g.dz)
+	return min(max(iy, 0), g.ny-1), min(max(iz, 0), g.nz-1)
+}
+
+func (g *yzGrid) candidates(y, z float64) []int {
+	if y < g.y0 || z < g.z0 || y > g.y0+g.dy*float64(g.ny) || z > g.z0+g.dz*float64(g.nz) {
+		return nil
+	}
+	iy, iz := g.index(y, z)
+	return g.cells[iz*g.ny+iy]
+}
+
+func (m *Mesh) String() string {
+	lo, hi := m.Bounds()
+	return fmt.Sprintf("%d triangles in [%g, %g] x [%g, %g] x [%g, %g]", len(m.Triangles), lo[0], hi[0], lo[1], hi[1], lo[2], hi[2])
+}
@@ -0,0 +1,129 @@
+package trimesh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+const cubeOBJ = `# unit cube
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0 0 1
+v 1 0 1
+v 1 1 1
+v 0 1 1
+f 1 4 3 2
+f 5 6 7 8
+f 1 2 6 5
+f 2/1 3/2 7/3 6/4
+f 3//1 4//1 8//1 7//1
+f -8 -4 -1 -5
+`
+
+func TestCube(t *testing.T) {
+	m, err := ReadOBJ(strings.NewReader(cubeOBJ))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Triangles) != 12 {
+		t.Fatalf("got %d triangles, want 12", len(m.Triangles))
+	}
+	if n := m.OpenEdges(); n != 0 {
+		t.Errorf("closed cube has %d open edges", n)
+	}
+	// lines through the diagonals of the faces must not be counted twice
+	for _, y := range []float64{-0.5, 0.25, 0.5, 1.5} {
+		for _, z := range []float64{-0.5, 0.5, 0.75, 1.5} {
+			for _, x := range []float64{-1, 0.5, 2} {
+				want := x > 0 && x < 1 && y > 0 && y < 1 && z > 0 && z < 1
+				if got := m.Inside(x, y, z); got != want {
+					t.Errorf("Inside(%g, %g, %g) = %v, want %v", x, y, z, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestVolume(t *testing.T) {
+	m, err := ReadOBJ(strings.NewReader(cubeOBJ))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Scale(2)
+	m.Translate([3]float64{-1, -1, -1})
+	if c := m.Center(); c != [3]float64{} {
+		t.Errorf("center = %v", c)
+	}
+	// fraction of a regular grid inside a cube of side 2 in [-2, 2]^3
+	n, inside := 40, 0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			for k := 0; k < n; k++ {
+				x, y, z := -2+4*(float64(i)+0.5)/float64(n), -2+4*(float64(j)+0.5)/float64(n), -2+4*(float64(k)+0.5)/float64(n)
+				if m.Inside(x, y, z) {
+					inside++
+				}
+			}
+		}
+	}
+	if f := float64(inside) / float64(n*n*n); math.Abs(f-1./8) > 1e-12 {
+		t.Errorf("inside fraction = %g, want 1/8", f)
+	}
+}
+
+var tetrahedron = [][3][3]float64{
+	{{0, 0, 0}, {0, 1, 0}, {1, 0, 0}},
+	{{0, 0, 0}, {1, 0, 0}, {0, 0, 1}},
+	{{0, 0, 0}, {0, 0, 1}, {0, 1, 0}},
+	{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+}
+
+func TestSTL(t *testing.T) {
+	var ascii strings.Builder
+	ascii.WriteString("solid tet\n")
+	for _, tr := range tetrahedron {
+		ascii.WriteString("facet normal 0 0 0\nouter loop\n")
+		for _, v := range tr {
+			fmt.Fprintf(&ascii, "vertex %g %g %g\n", v[0], v[1], v[2])
+		}
+		ascii.WriteString("endloop\nendfacet\n")
+	}
+	ascii.WriteString("endsolid tet\n")
+
+	var bin bytes.Buffer
+	bin.Write(make([]byte, 80))
+	_ = binary.Write(&bin, binary.LittleEndian, uint32(len(tetrahedron)))
+	for _, tr := range tetrahedron {
+		_ = binary.Write(&bin, binary.LittleEndian, [3]float32{})
+		for _, v := range tr {
+			_ = binary.Write(&bin, binary.LittleEndian, [3]float32{float32(v[0]), float32(v[1]), float32(v[2])})
+		}
+		_ = binary.Write(&bin, binary.LittleEndian, uint16(0))
+	}
+
+	for name, data := range map[string][]byte{"ascii": []byte(ascii.String()), "binary": bin.Bytes()} {
+		m, err := ReadSTL(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(m.Triangles) != 4 || m.OpenEdges() != 0 {
+			t.Errorf("%s: %d triangles, %d open edges", name, len(m.Triangles), m.OpenEdges())
+		}
+		if !m.Inside(0.1, 0.1, 0.1) || m.Inside(0.5, 0.5, 0.5) || m.Inside(-0.1, 0.1, 0.1) {
+			t.Errorf("%s: wrong inside test", name)
+		}
+	}
+}
+
+func TestOpenEdges(t *testing.T) {
+	m := New(tetrahedron[:3])
+	if n := m.OpenEdges(); n != 3 {
+		t.Errorf("got %d open edges, want 3", n)
+	}
+}
@@ -0,0 +1,109 @@
+package zarr
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DataDog/zstd"
+
+	"github.com/MathieuMoalic/amumax/src/data"
+)
+
+// Dataset is a quantity saved by AutoSave or Save: a float32 array of shape [t, z, y, x, comp].
+type Dataset struct {
+	Path   string
+	Shape  [5]int
+	Chunks [5]int
+	Times  []float64
+}
+
+// OpenDataset reads the metadata of the dataset stored in dir.
+func OpenDataset(dir string) (*Dataset, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ".zarray"))
+	if err != nil {
+		return nil, err
+	}
+	var z zarrayFile
+	if err := json.Unmarshal(content, &z); err != nil {
+		return nil, fmt.Errorf("%s: invalid .zarray: %v", dir, err)
+	}
+	if z.Dtype != "<f4" || z.Compressor.ID != "zstd" {
+		return nil, fmt.Errorf("%s: only zstd compressed float32 datasets are supported", dir)
+	}
+	d := &Dataset{Path: dir, Shape: z.Shape, Chunks: z.Chunks}
+	if content, err := os.ReadFile(filepath.Join(dir, ".zattrs")); err == nil {
+		var attrs Zattrs
+		if err := json.Unmarshal(content, &attrs); err != nil {
+			return nil, fmt.Errorf("%s: invalid .zattrs: %v", dir, err)
+		}
+		d.Times = attrs.Buffer
+	}
+	return d, nil
+}
+
+// Steps returns the number of saved time steps.
+func (d *Dataset) Steps() int {
+	return d.Shape[0]
+}
+
+// Size returns the mesh size {Nx, Ny, Nz}.
+func (d *Dataset) Size() [3]int {
+	return [3]int{d.Shape[3], d.Shape[2], d.Shape[1]}
+}
+
+// NComp returns the number of components.
+func (d *Dataset) NComp() int {
+	return d.Shape[4]
+}
+
+// Time returns the simulation time of step t, or 0 if it was not recorded.
+func (d *Dataset) Time(t int) float64 {
+	if t < len(d.Times) {
+		return d.Times[t]
+	}
+	return 0
+}
+
+// ReadStep reads time step t, assembling all its chunks.
+func (d *Dataset) ReadStep(t int) (*data.Slice, error) {
+	if t < 0 || t >= d.Steps() {
+		return nil, fmt.Errorf("%s: time step %d out of range [0, %d)", d.Path, t, d.Steps())
+	}
+	s := data.NewSlice(d.NComp(), d.Size())
+	tensors := s.Tensors()
+	cz, cy, cx, cc := d.Chunks[1], d.Chunks[2], d.Chunks[3], d.Chunks[4]
+	for iz := 0; iz < d.Shape[1]/cz; iz++ {
+		for iy := 0; iy < d.Shape[2]/cy; iy++ {
+			for ix := 0; ix < d.Shape[3]/cx; ix++ {
+				for ic := 0; ic < d.Shape[4]/cc; ic++ {
+					name := filepath.Join(d.Path, fmt.Sprintf("%d.%d.%d.%d.%d", t, iz, iy, ix, ic))
+					compressed, err := os.ReadFile(name)
+					if err != nil {
+						return nil, err
+					}
+					raw, err := zstd.Decompress(nil, compressed)
+					if err != nil {
+						return nil, fmt.Errorf("%s: %v", name, err)
+					}
+					if len(raw) != 4*cz*cy*cx*cc {
+						return nil, fmt.Errorf("%s: unexpected chunk size", name)
+					}
+					k := 0
+					for z := iz * cz; z < (iz+1)*cz; z++ {
+						for y := iy * cy; y < (iy+1)*cy; y++ {
+							for x := ix * cx; x < (ix+1)*cx; x++ {
+								for c := ic * cc; c < (ic+1)*cc; c++ {
+									tensors[c][z][y][x] = BytesToFloat32(raw[k : k+4])
+									k += 4
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return s, nil
+}
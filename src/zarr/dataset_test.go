@@ -0,0 +1,133 @@
+package zarr
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/zstd"
+)
+
+// writeDataset writes a dataset of shape [t, z, y, x, comp] split in chunks, where the value of each
+// element is given by value.
+func writeDataset(t *testing.T, dir string, shape, chunks [5]int, times []float64, value func(it, iz, iy, ix, ic int) float32) {
+	put := func(name string, v any) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put(".zarray", zarrayFile{
+		Chunks:     chunks,
+		Compressor: ZstdCompressor{"zstd", 1},
+		Dtype:      "<f4",
+		Order:      "C",
+		Shape:      shape,
+		ZarrFormat: 2,
+	})
+	put(".zattrs", Zattrs{Buffer: times})
+
+	var n [5]int
+	for k := range n {
+		n[k] = shape[k] / chunks[k]
+	}
+	for it := 0; it < n[0]; it++ {
+		for iz := 0; iz < n[1]; iz++ {
+			for iy := 0; iy < n[2]; iy++ {
+				for ix := 0; ix < n[3]; ix++ {
+					for ic := 0; ic < n[4]; ic++ {
+						var raw []byte
+						for z := iz * chunks[1]; z < (iz+1)*chunks[1]; z++ {
+							for y := iy * chunks[2]; y < (iy+1)*chunks[2]; y++ {
+								for x := ix * chunks[3]; x < (ix+1)*chunks[3]; x++ {
+									for c := ic * chunks[4]; c < (ic+1)*chunks[4]; c++ {
+										raw = binary.LittleEndian.AppendUint32(raw, math.Float32bits(value(it, z, y, x, c)))
+									}
+								}
+							}
+						}
+						compressed, err := zstd.Compress(nil, raw)
+						if err != nil {
+							t.Fatal(err)
+						}
+						name := fmt.Sprintf("%d.%d.%d.%d.%d", it, iz, iy, ix, ic)
+						if err := os.WriteFile(filepath.Join(dir, name), compressed, 0o644); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDatasetReadStep(t *testing.T) {
+	value := func(it, iz, iy, ix, ic int) float32 {
+		return float32(it*10000+iz*1000+iy*100+ix*10+ic) + 0.5
+	}
+	tests := []struct {
+		name          string
+		shape, chunks [5]int
+	}{
+		{"one chunk per step", [5]int{2, 2, 3, 4, 3}, [5]int{1, 2, 3, 4, 3}},
+		{"chunks in every direction", [5]int{2, 2, 4, 6, 3}, [5]int{1, 1, 2, 3, 1}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeDataset(t, dir, tt.shape, tt.chunks, []float64{0, 1e-12}, value)
+
+		d, err := OpenDataset(dir)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if d.Steps() != tt.shape[0] || d.NComp() != tt.shape[4] || d.Size() != [3]int{tt.shape[3], tt.shape[2], tt.shape[1]} {
+			t.Errorf("%s: %d steps of %v cells and %d components, want the shape %v", tt.name, d.Steps(), d.Size(), d.NComp(), tt.shape)
+		}
+		if d.Time(1) != 1e-12 || d.Time(5) != 0 {
+			t.Errorf("%s: times %g, %g, want 1e-12 and 0 past the recorded ones", tt.name, d.Time(1), d.Time(5))
+		}
+		for it := 0; it < d.Steps(); it++ {
+			s, err := d.ReadStep(it)
+			if err != nil {
+				t.Fatalf("%s: step %d: %v", tt.name, it, err)
+			}
+			tensors := s.Tensors()
+			for ic := range tensors {
+				for iz := range tensors[ic] {
+					for iy := range tensors[ic][iz] {
+						for ix, got := range tensors[ic][iz][iy] {
+							if want := value(it, iz, iy, ix, ic); got != want {
+								t.Errorf("%s: step %d, cell (%d, %d, %d), component %d: %g, want %g", tt.name, it, ix, iy, iz, ic, got, want)
+							}
+						}
+					}
+				}
+			}
+		}
+		if _, err := d.ReadStep(tt.shape[0]); err == nil {
+			t.Errorf("%s: read a step past the end", tt.name)
+		}
+	}
+}
+
+func TestDatasetMissingChunk(t *testing.T) {
+	dir := t.TempDir()
+	writeDataset(t, dir, [5]int{1, 1, 2, 2, 1}, [5]int{1, 1, 1, 2, 1}, nil, func(_, _, _, _, _ int) float32 { return 1 })
+	if err := os.Remove(filepath.Join(dir, "0.0.1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	d, err := OpenDataset(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.ReadStep(0); err == nil {
+		t.Error("read a step with a missing chunk")
+	}
+}
@@ -56,3 +56,20 @@ func pathExists(path string) bool {
 	}
 	return true
 }
+
+// SaveFloat64Array writes values as a single-chunk 1-D array in dir, replacing any previous content.
+func SaveFloat64Array(dir string, values []float64) {
+	if err := fsutil.Mkdir(dir); err != nil && !os.IsExist(err) {
+		log.Log.PanicIfError(err)
+	}
+	buf := make([]byte, 0, 8*len(values))
+	for _, v := range values {
+		buf = append(buf, Float64ToBytes(v)...)
+	}
+	f, err := fsutil.Create(dir + "/0")
+	log.Log.PanicIfError(err)
+	_, err = f.Write(buf)
+	log.Log.PanicIfError(err)
+	log.Log.PanicIfError(f.Close())
+	SaveFileTableZarray(dir, len(values)-1)
+}
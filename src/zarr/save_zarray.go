@@ -55,3 +55,31 @@ func SaveFileZarray(path string, size [3]int, ncomp int, step int, cz int, cy in
 	err = f.Flush()
 	log.Log.PanicIfError(err)
 }
+
+// SaveFileSpectrumZarray writes the .zarray of a complex64 dataset of nf frequencies of the mesh,
+// with one chunk per frequency.
+func SaveFileSpectrumZarray(path string, size [3]int, ncomp int, nf int) {
+	z := zarrayFile{}
+	z.Compressor = ZstdCompressor{"zstd", 1}
+	z.Dtype = `<c8`
+	z.FillValue = 0.0
+	z.Order = "C"
+	z.ZarrFormat = 2
+	z.Chunks = [5]int{1, size[2], size[1], size[0], ncomp}
+	z.Shape = [5]int{nf, size[2], size[1], size[0], ncomp}
+
+	f, err := fsutil.Create(path)
+	log.Log.PanicIfError(err)
+	defer func() {
+		cerr := f.Close()
+		if cerr != nil {
+			log.Log.Err("Error closing zarray file: %v", cerr)
+		}
+	}()
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	log.Log.PanicIfError(enc.Encode(z))
+	err = f.Flush()
+	log.Log.PanicIfError(err)
+}
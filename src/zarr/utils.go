@@ -2,6 +2,7 @@
 package zarr
 
 import (
+	"encoding/json"
 	"strings"
 
 	"github.com/MathieuMoalic/amumax/src/fsutil"
@@ -34,3 +35,19 @@ func InitZgroup(name string, od string) {
 	_, err = zgroup.Write([]byte("{\"zarr_format\": 2}"))
 	log.Log.PanicIfError(err)
 }
+
+// SaveZattrs writes attrs as the .zattrs file of the group or array in dir.
+func SaveZattrs(dir string, attrs map[string]any) {
+	u, err := json.MarshalIndent(attrs, "", "\t")
+	log.Log.PanicIfError(err)
+	f, err := fsutil.Create(dir + "/.zattrs")
+	log.Log.PanicIfError(err)
+	defer func() {
+		cerr := f.Close()
+		if cerr != nil {
+			log.Log.Err("Error closing zattrs file: %v", cerr)
+		}
+	}()
+	_, err = f.Write(u)
+	log.Log.PanicIfError(err)
+}